@@ -1,49 +1,59 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/jasonuc/gignr/internal/cache"
+	"github.com/jasonuc/gignr/internal/templates"
 	"github.com/jasonuc/gignr/internal/tui"
 	"github.com/jasonuc/gignr/internal/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-type GitHubContentResponse []struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
-}
-
 var nickname string
 var repoURL string
+var apiURL string
+var rawURL string
+var repoType string
 
 var addCmd = &cobra.Command{
-	Use:     "add <url> -nickname <nickname>",
-	Short:   "Add a custom GitHub repository with .gitignore templates",
-	Long:    "Add a custom GitHub repository containing .gitignore templates. Once added, the repository will be used as a source for fetching templates.",
-	Example: "gignr add https://github.com/jasonuc/gitignore -nickname jc",
+	Use:     "add <url|path> -nickname <nickname>",
+	Short:   "Add a custom repository with .gitignore templates",
+	Long:    "Add a custom GitHub, GitLab, Gitea or Bitbucket repository, git remote, local directory, index, gist or archive containing .gitignore templates. Once added, the repository will be used as a source for fetching templates.\n\nThe type of repository is detected from github.com, gitlab.com, codeberg.org and bitbucket.org URLs. Repositories on other hosts are fetched through the GitHub Enterprise API at https://{host}/api/v3, with raw files from https://{host}/raw, unless --type is given. Use --api-url and --raw-url if your server differs.",
+	Example: "gignr add https://github.com/jasonuc/gitignore -nickname jc\ngignr add https://ghe.corp/platform/gitignore -nickname corp --api-url https://ghe.corp/api/v3\ngignr add https://git.corp/platform/gitignore -nickname corp --type gitlab\ngignr add ./shared/gitignore-templates -nickname team",
 	Args:    cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		repoURL = args[0]
+		repoCfg := templates.RepositoryConfig{URL: repoURL, Type: repoType, APIURL: apiURL, RawURL: rawURL}
+
+		// Directories and local archives are stored as absolute paths so
+		// they resolve from anywhere
+		if kind := repoCfg.Kind(); kind == templates.KindDir || kind == templates.KindArchive && templates.IsLocalPath(repoURL) {
+			dir, err := templates.ExpandPath(repoURL)
+			if err != nil {
+				utils.PrintError(fmt.Sprintf("Invalid path: %v", err))
+				return
+			}
+			repoURL, repoCfg.URL = dir, dir
+		}
 
-		if !utils.IsValidGitHubURL(repoURL) {
-			utils.PrintError("Invalid GitHub URL. Must be in format: https://github.com/{user}/{repo}")
+		if repoCfg.Kind() == templates.KindGitHub && !utils.IsValidRepositoryURL(repoURL) {
+			utils.PrintError("Invalid repository URL. Must be in format: https://{host}/{user}/{repo}")
 			return
 		}
 		if !utils.IsValidNickname(nickname) {
 			utils.PrintError("Invalid nickname. Must be alphanumeric and contain no spaces.")
 			return
 		}
-		if utils.IsReservedNickname(nickname) {
-			utils.PrintError("Invalid nickname. Reserved names: gh, ghc, ghg, tt.")
+		if templates.IsReservedPrefix(nickname) {
+			utils.PrintError(fmt.Sprintf("Invalid nickname. Reserved names: %s.", strings.Join(templates.ReservedPrefixes(), ", ")))
 			return
 		}
 
-		hasTemplates, err := validateGitignoreTemplates(repoURL)
+		initSources()
+		hasTemplates, err := validateGitignoreTemplates(repoCfg)
 		if err != nil {
 			utils.PrintError(fmt.Sprintf("Failed to validate repository: %v", err))
 			return
@@ -53,7 +63,7 @@ var addCmd = &cobra.Command{
 			return
 		}
 
-		repos := viper.GetStringMapString("repositories")
+		repos := viper.GetStringMap("repositories")
 
 		if _, exists := repos[nickname]; exists {
 			prompt := fmt.Sprintf("The nickname '%s' already exists. Overwrite?\n%s → %s", nickname, nickname, repoURL)
@@ -63,7 +73,21 @@ var addCmd = &cobra.Command{
 			}
 		}
 
-		repos[nickname] = repoURL
+		if repoType == "" && apiURL == "" && rawURL == "" {
+			repos[nickname] = repoURL
+		} else {
+			entry := map[string]string{"url": repoURL}
+			if repoType != "" {
+				entry["type"] = repoType
+			}
+			if apiURL != "" {
+				entry["api_url"] = apiURL
+			}
+			if rawURL != "" {
+				entry["raw_url"] = rawURL
+			}
+			repos[nickname] = entry
+		}
 		viper.Set("repositories", repos)
 
 		cache.UpdateCacheNeedRefreshStatus(true)
@@ -80,41 +104,17 @@ var addCmd = &cobra.Command{
 func init() {
 	addCmd.Flags().StringVarP(&nickname, "nickname", "n", "", "Nickname for the repository")
 	addCmd.MarkFlagRequired("nickname")
+	addCmd.Flags().StringVarP(&repoType, "type", "t", "", fmt.Sprintf("Type of repository (%s), detected from the URL when omitted", strings.Join(templates.RepositoryKinds, ", ")))
+	addCmd.Flags().StringVar(&apiURL, "api-url", "", "Base URL of the API, e.g. https://ghe.corp/api/v3")
+	addCmd.Flags().StringVar(&rawURL, "raw-url", "", "Base URL raw files are served from, GitHub only, e.g. https://ghe.corp/raw")
 	rootCmd.AddCommand(addCmd)
 }
 
-func validateGitignoreTemplates(repoURL string) (bool, error) {
-	parts := strings.Split(strings.TrimSuffix(repoURL, "/"), "/")
-	if len(parts) < 2 {
-		return false, fmt.Errorf("invalid GitHub URL format")
-	}
-	owner := parts[len(parts)-2]
-	repo := parts[len(parts)-1]
-
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/contents", owner, repo)
-
-	resp, err := http.Get(apiURL)
+func validateGitignoreTemplates(cfg templates.RepositoryConfig) (bool, error) {
+	src, err := templates.NewRepositorySource(nickname, cfg)
 	if err != nil {
-		return false, fmt.Errorf("failed to fetch repository contents: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf("failed to fetch repository contents: %s", resp.Status)
-	}
-
-	var contents GitHubContentResponse
-	if err := json.NewDecoder(resp.Body).Decode(&contents); err != nil {
-		return false, fmt.Errorf("failed to parse repository contents: %v", err)
-	}
-
-	var hasTemplates bool
-	for _, item := range contents {
-		if item.Type == "file" && strings.HasSuffix(item.Name, ".gitignore") && item.Name != ".gitignore" {
-			hasTemplates = true
-			break
-		}
+		return false, err
 	}
 
-	return hasTemplates, nil
+	return src.HasGitignoreTemplates()
 }
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jasonuc/gignr/internal/cache"
+	"github.com/jasonuc/gignr/internal/templates"
+	"github.com/jasonuc/gignr/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// warmWorkers is how many templates are downloaded at once by cache warm
+const warmWorkers = 8
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and manage the template cache",
+	Long: fmt.Sprintf(`Template listings and contents are cached under ~/.config/gignr/cache
+for %s, and refreshed in the background once they expire.`, cache.TTL),
+}
+
+var cacheStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the age, entry count and size of every cache file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		files, err := cache.Files()
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Unable to read the cache: %v", err))
+			return
+		}
+		if len(files) == 0 {
+			utils.PrintAlert("The cache is empty")
+			return
+		}
+
+		fmt.Println("Cache directory:", cache.GetCacheDir())
+		fmt.Println()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tAGE\tENTRIES\tSIZE")
+		var total int64
+		for _, f := range files {
+			name, age := f.Name, formatAge(time.Since(f.Updated))
+			if f.IsDir {
+				name += "/"
+			} else if cache.IsCacheExpired(f.Updated) {
+				age += " " + color.New(color.FgYellow).Sprint("(expired)")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", name, age, f.Entries, formatSize(f.Size))
+			total += f.Size
+		}
+		w.Flush()
+
+		fmt.Printf("\n%d files, %s in total\n", len(files), formatSize(total))
+	},
+}
+
+var cacheRefreshCmd = &cobra.Command{
+	Use:     "refresh [source]",
+	Example: "gignr cache refresh\ngignr cache refresh gh",
+	Short:   "Re-fetch the template listings of every source, or of one source",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if cache.Offline() {
+			utils.PrintError("Cannot refresh the cache while offline.")
+			return
+		}
+
+		initSources()
+		sources, err := cacheSources(args)
+		if err != nil {
+			utils.PrintError(err.Error())
+			return
+		}
+
+		var refreshed, failed int
+		for _, src := range sources {
+			refresher, ok := src.(templates.Refresher)
+			if !ok {
+				if len(args) > 0 {
+					utils.PrintError(fmt.Sprintf("%s is read directly and has no cache to refresh", src.DisplayName()))
+					return
+				}
+				continue
+			}
+
+			if err := refresher.Refresh(); err != nil {
+				utils.PrintWarning(fmt.Sprintf("Failed to refresh %s: %v", src.DisplayName(), err))
+				failed++
+				continue
+			}
+			refreshed++
+		}
+
+		if len(args) == 0 && failed == 0 {
+			cache.UpdateCacheNeedRefreshStatus(false)
+		}
+		if refreshed > 0 {
+			utils.PrintSuccess(fmt.Sprintf("Refreshed %d %s", refreshed, plural(refreshed, "source", "sources")))
+		}
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove everything from the cache",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cache.Clear(); err != nil {
+			utils.PrintError(fmt.Sprintf("Unable to clear the cache: %v", err))
+			return
+		}
+		utils.PrintSuccess(fmt.Sprintf("Cleared %s", cache.GetCacheDir()))
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove expired template contents from the cache",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pruned, err := templates.PruneTemplateContent()
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Unable to prune the cache: %v", err))
+			return
+		}
+		if pruned == 0 {
+			utils.PrintAlert("No expired template contents found")
+			return
+		}
+		utils.PrintSuccess(fmt.Sprintf("Removed %d expired template %s", pruned, plural(pruned, "content", "contents")))
+	},
+}
+
+var cacheWarmCmd = &cobra.Command{
+	Use:     "warm [source]",
+	Example: "gignr cache warm\ngignr cache warm tt",
+	Short:   "Download the content of every listed template ahead of time",
+	Long: `The warm command lists the templates of every source, or of one source,
+and downloads the content of those that are not cached or have expired, so
+they can be used later without a network.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if cache.Offline() {
+			utils.PrintError("Cannot warm the cache while offline.")
+			return
+		}
+
+		initSources()
+		sources, err := cacheSources(args)
+		if err != nil {
+			utils.PrintError(err.Error())
+			return
+		}
+
+		seen := map[string]bool{}
+		var urls []string
+		for _, src := range sources {
+			list, err := src.List()
+			if err != nil {
+				utils.PrintWarning(fmt.Sprintf("Failed to fetch templates from %s: %v", src.DisplayName(), err))
+				continue
+			}
+			// Templates without a download URL are read from disk
+			for _, tmpl := range list {
+				if tmpl.DownloadURL != "" && !seen[tmpl.DownloadURL] {
+					seen[tmpl.DownloadURL] = true
+					urls = append(urls, tmpl.DownloadURL)
+				}
+			}
+		}
+
+		downloaded, failed := warmContent(urls)
+		for _, err := range failed {
+			utils.PrintWarning(err.Error())
+		}
+		utils.PrintSuccess(fmt.Sprintf("Downloaded %d of %d templates, %d were already cached",
+			downloaded, len(urls), len(urls)-downloaded-len(failed)))
+	},
+}
+
+// cacheSources returns the source named in args, or every source except
+// local templates
+func cacheSources(args []string) ([]templates.Source, error) {
+	if len(args) > 0 {
+		src, err := templates.Lookup(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return []templates.Source{src}, nil
+	}
+
+	var sources []templates.Source
+	for _, src := range templates.Sources() {
+		if src.Prefix() != "" {
+			sources = append(sources, src)
+		}
+	}
+	return sources, nil
+}
+
+// warmContent downloads the given templates in parallel and returns how
+// many were downloaded along with the failures
+func warmContent(urls []string) (int, []error) {
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		downloaded int
+		failed     []error
+	)
+
+	queue := make(chan string)
+	for i := 0; i < warmWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range queue {
+				fetched, err := templates.WarmTemplateContent(url)
+
+				mu.Lock()
+				if err != nil {
+					failed = append(failed, fmt.Errorf("failed to download %s: %v", url, err))
+				} else if fetched {
+					downloaded++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, url := range urls {
+		queue <- url
+	}
+	close(queue)
+	wg.Wait()
+
+	return downloaded, failed
+}
+
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size < 1<<10:
+		return fmt.Sprintf("%d B", size)
+	case size < 1<<20:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+	}
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatusCmd, cacheRefreshCmd, cacheClearCmd, cachePruneCmd, cacheWarmCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
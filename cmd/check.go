@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/jasonuc/gignr/internal/matcher"
+	"github.com/jasonuc/gignr/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:     "check <path>...",
+	Example: "gignr check build/ main.go",
+	Args:    cobra.MinimumNArgs(1),
+	Short:   "Check whether paths are ignored by the .gitignore files",
+	Long: `The check command evaluates each path against the .gitignore files of the
+working tree, including nested .gitignore files, and prints whether it is
+ignored. Paths that do not exist are treated as directories when they end
+with a slash.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		m, err := newMatcher()
+		if err != nil {
+			utils.PrintError(err.Error())
+			return
+		}
+
+		for _, arg := range args {
+			res, err := matchArg(m, arg)
+			if err != nil {
+				utils.PrintError(fmt.Sprintf("Unable to check %s: %v", arg, err))
+				continue
+			}
+
+			if res.Ignored {
+				fmt.Println(color.New(color.FgRed).Sprint("ignored    "), arg)
+			} else {
+				fmt.Println(color.New(color.FgGreen).Sprint("not ignored"), arg)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
+
+func newMatcher() (*matcher.Matcher, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine the current directory: %v", err)
+	}
+	return matcher.New(matcher.FindRoot(cwd)), nil
+}
+
+func matchArg(m *matcher.Matcher, arg string) (matcher.Result, error) {
+	rel, err := m.Rel(arg)
+	if err != nil {
+		return matcher.Result{}, err
+	}
+
+	isDir := strings.HasSuffix(arg, "/")
+	if info, err := os.Stat(arg); err == nil {
+		isDir = info.IsDir()
+	}
+
+	return m.Match(rel, isDir)
+}
@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
-	"github.com/Delta456/box-cli-maker/v2"
+	"github.com/jasonuc/gignr/internal/auth"
+	"github.com/jasonuc/gignr/internal/gitignore"
+	"github.com/jasonuc/gignr/internal/lock"
 	"github.com/jasonuc/gignr/internal/templates"
 	"github.com/jasonuc/gignr/internal/tui"
 	"github.com/jasonuc/gignr/internal/utils"
@@ -13,10 +17,15 @@ import (
 )
 
 var createCmd = &cobra.Command{
-	Use:     "create <template> [templates]...",
-	Example: "gignr create gh:Go tt:clion my-template",
-	Args:    cobra.MinimumNArgs(1),
-	Short:   "Generate a .gitignore file using one or more templates",
+	Use:     "create [template]...",
+	Example: "gignr create gh:Go tt:clion my-template\ngignr create gh:Go@main ghg:macOS@a1b2c3d\ngignr create --append gh:Python",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if autoDetect {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	Short: "Generate a .gitignore file using one or more templates",
 	Long: `The create command generates a .gitignore file based on one or more templates of your choice.
 
 Available templates are identified by prefixes:
@@ -25,8 +34,37 @@ Available templates are identified by prefixes:
   - ghg: GitHub Global templates
   - ghc: GitHub Community templates
   - (no prefix) → Fetch from local saved templates
+
+Append @ref to a remote template to fetch it at a commit SHA, tag or
+branch instead of the default branch, e.g. gh:Go@main.
+
+Each template is written between "# >>> gignr <template>" and
+"# <<< gignr <template>" markers so it can be refreshed later with
+"gignr update". Use --append to add templates to an existing .gitignore,
+templates that already have a block are skipped. Use --auto to add the
+templates suggested by "gignr detect" for the current project.
+
+Templates fetched from GitHub are pinned to the commit they were fetched
+at in gignr.lock, and later runs reproduce the same content from it. Use
+--upgrade to fetch the latest content and move the pins forward.
+
+Patterns repeated across templates are reported, and removed with
+--dedupe. A warning is shown when a later template ignores a path that
+an earlier one re-included with "!".
 `,
 	Run: func(cmd *cobra.Command, args []string) {
+		if autoDetect {
+			var ok bool
+			if args, ok = withDetectedTemplates(args); !ok {
+				return
+			}
+		}
+
+		if appendTemplates {
+			runAppend(args)
+			return
+		}
+
 		// Check for existing .gitignore
 		if content, err := os.ReadFile(".gitignore"); err == nil && len(content) > 0 {
 			if !tui.RunConfirmation("A .gitignore file already exists. Do you want to overwrite it?") {
@@ -35,144 +73,274 @@ Available templates are identified by prefixes:
 			}
 		}
 
-		var mergedContent strings.Builder
+		pins, err := lock.Load(lock.FileName)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Unable to read %s: %v", lock.FileName, err))
+			return
+		}
+		if upgradePins {
+			pins.Retain(nil)
+		}
+
+		file := &gitignore.File{}
 		var hasErrors bool
 
-		templates.InitGitHubClient("")
-		repos := templates.LoadCustomRepositories()
+		initSources()
 
 		for _, arg := range args {
-			content, err := processTemplate(arg, repos)
+			content, err := processTemplate(arg, pins)
 			if err != nil {
 				utils.PrintError(fmt.Sprintf("Error processing %s: %v", arg, err))
 				hasErrors = true
 				continue
 			}
 
-			addTemplateToContent(&mergedContent, arg, content)
+			file.Set(arg, content)
 		}
 
 		if hasErrors {
 			utils.PrintWarning("Some templates failed to process. .gitignore file will be incomplete.")
 		}
 
-		if err := os.WriteFile(".gitignore", []byte(mergedContent.String()), 0644); err != nil {
+		reviewMerge(file, dedupePatterns)
+
+		if err := os.WriteFile(".gitignore", file.Render(), 0644); err != nil {
 			utils.PrintError(fmt.Sprintf("Failed to write .gitignore file: %v", err))
 			return
 		}
 
+		pins.Retain(args)
+		savePins(pins)
+
 		utils.PrintSuccess("Created .gitignore!")
 	},
 }
 
+var appendTemplates bool
+var dedupePatterns bool
+var autoDetect bool
+var upgradePins bool
+
 func init() {
+	createCmd.Flags().BoolVar(&upgradePins, "upgrade", false, "Fetch the latest template content instead of the revisions pinned in "+lock.FileName)
+	createCmd.Flags().BoolVar(&autoDetect, "auto", false, "Add the templates suggested by gignr detect")
+	createCmd.Flags().BoolVar(&dedupePatterns, "dedupe", false, "Remove patterns already provided by an earlier template")
+	createCmd.Flags().BoolVarP(&appendTemplates, "append", "a", false, "Add the templates to an existing .gitignore instead of overwriting it")
 	rootCmd.AddCommand(createCmd)
 }
 
-type TemplateSource struct {
-	owner string
-	repo  string
-	path  string
-}
+// runAppend adds templates that are not yet present to the existing
+// .gitignore, keeping everything already in the file
+func runAppend(args []string) {
+	data, err := os.ReadFile(".gitignore")
+	if err != nil && !os.IsNotExist(err) {
+		utils.PrintError(fmt.Sprintf("Unable to read .gitignore: %v", err))
+		return
+	}
 
-func resolveTemplateSource(prefix string, repos map[string]string) (*TemplateSource, error) {
-	switch prefix {
-	case "tt":
-		return &TemplateSource{"toptal", "gitignore", "templates"}, nil
-	case "gh":
-		return &TemplateSource{"github", "gitignore", ""}, nil
-	case "ghc":
-		return &TemplateSource{"github", "gitignore", "community"}, nil
-	case "ghg":
-		return &TemplateSource{"github", "gitignore", "Global"}, nil
-	default:
-		// Check user-defined repos
-		if repoURL, exists := repos[prefix]; exists {
-			owner, repo, err := utils.ExtractRepoDetails(repoURL)
-			if err != nil {
-				return nil, fmt.Errorf("invalid repository URL for prefix %s", prefix)
-			}
-			return &TemplateSource{owner, repo, ""}, nil
+	file, err := gitignore.Parse(data)
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Unable to parse .gitignore: %v", err))
+		return
+	}
+
+	pins, err := lock.Load(lock.FileName)
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Unable to read %s: %v", lock.FileName, err))
+		return
+	}
+
+	initSources()
+
+	var added, skipped []string
+	for _, arg := range args {
+		if file.Has(arg) || slices.Contains(added, arg) {
+			skipped = append(skipped, arg)
+			continue
+		}
+
+		if upgradePins {
+			pins.Remove(arg)
+		}
+
+		content, err := processTemplate(arg, pins)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Error processing %s: %v", arg, err))
+			continue
 		}
-		return nil, fmt.Errorf("unknown template prefix or missing repository: %s", prefix)
+
+		file.Set(arg, content)
+		added = append(added, arg)
+	}
+
+	if len(skipped) > 0 {
+		utils.PrintAlert(fmt.Sprintf("Already present, skipped: %s", strings.Join(skipped, ", ")))
+	}
+	if len(added) == 0 {
+		utils.PrintAlert(".gitignore file has not been modified")
+		return
 	}
+
+	reviewMerge(file, dedupePatterns)
+
+	if err := os.WriteFile(".gitignore", file.Render(), 0644); err != nil {
+		utils.PrintError(fmt.Sprintf("Failed to write .gitignore file: %v", err))
+		return
+	}
+
+	savePins(pins)
+
+	utils.PrintSuccess(fmt.Sprintf("Added to .gitignore: %s", strings.Join(added, ", ")))
 }
 
-func findTemplate(templateName string, templates []templates.Template) (string, error) {
-	// Try exact match first
-	for _, tmpl := range templates {
-		if tmpl.Name == templateName+".gitignore" {
-			return tmpl.DownloadURL, nil
-		}
+// withDetectedTemplates appends the templates suggested for the current
+// project to args, skipping any already given
+func withDetectedTemplates(args []string) ([]string, bool) {
+	suggestions, err := detectTemplates()
+	if err != nil {
+		utils.PrintError(err.Error())
+		return nil, false
 	}
 
-	// Try case-insensitive match
-	for _, tmpl := range templates {
-		if strings.EqualFold(tmpl.Name, templateName+".gitignore") {
-			return tmpl.DownloadURL, nil
+	var detected []string
+	for _, s := range suggestions {
+		if !slices.Contains(args, s.Template) {
+			args = append(args, s.Template)
+			detected = append(detected, s.Template)
 		}
 	}
 
-	return "", fmt.Errorf("template %s not found", templateName)
+	if len(args) == 0 {
+		utils.PrintAlert("No known project files found, nothing to create")
+		return nil, false
+	}
+	if len(detected) > 0 {
+		utils.PrintAlert(fmt.Sprintf("Detected templates: %s", strings.Join(detected, ", ")))
+	}
+	return args, true
+}
+
+// reviewMerge warns about negation conflicts between templates and
+// reports, or with --dedupe removes, duplicate patterns
+func reviewMerge(file *gitignore.File, dedupe bool) {
+	printMergeReport(mergeFile(file, dedupe), dedupe)
 }
 
-func createTemplateBox() box.Box {
-	config := box.Config{Px: 1, Py: 1, Type: "", TitlePos: "Inside"}
-	return box.Box{
-		TopRight: "*", TopLeft: "*",
-		BottomRight: "*", BottomLeft: "*",
-		Horizontal: "-", Vertical: "|",
-		Config: config,
+func mergeFile(file *gitignore.File, dedupe bool) gitignore.MergeReport {
+	if dedupe {
+		return file.Dedupe()
 	}
+	return file.Analyze()
 }
 
-func addTemplateToContent(builder *strings.Builder, templateName string, content []byte) {
-	boxTitle := fmt.Sprintf(" %s",
-		strings.ToUpper(templateName))
+func printMergeReport(report gitignore.MergeReport, dedupe bool) {
+	for _, c := range report.Conflicts {
+		utils.PrintWarning(fmt.Sprintf("%s re-includes !%s but %s ignores it again with %s", c.Negated, c.Pattern, c.Template, c.Rule))
+	}
 
-	box := createTemplateBox()
-	builder.WriteString(box.String("", boxTitle))
-	builder.Write(content)
-	builder.WriteString("\n\n")
+	if len(report.Duplicates) == 0 {
+		return
+	}
+	if dedupe {
+		utils.PrintAlert(fmt.Sprintf("Removed %d duplicate pattern(s)", len(report.Duplicates)))
+	} else {
+		utils.PrintAlert(fmt.Sprintf("Found %d duplicate pattern(s), use --dedupe to remove them", len(report.Duplicates)))
+	}
 }
 
-func processTemplate(arg string, repos map[string]string) (content []byte, err error) {
-	if strings.Contains(arg, ":") {
-		// Handle remote templates (gh:, tt:, etc)
-		parts := strings.SplitAfter(arg, ":")
-		prefix := strings.TrimSpace(parts[0][:len(parts[0])-1])
-		templateName := strings.TrimSpace(parts[1])
+// initSources prepares the GitHub client and registers the user-added
+// repositories as template sources
+func initSources() {
+	if err := templates.InitGitHubClient(auth.GitHubToken(auth.DefaultHost)); err != nil {
+		utils.PrintWarning(err.Error())
+	}
+	for _, err := range templates.LoadCustomSources() {
+		utils.PrintWarning(err.Error())
+	}
+	for _, err := range templates.LoadPluginSources() {
+		utils.PrintWarning(err.Error())
+	}
+}
 
-		src, err := resolveTemplateSource(prefix, repos)
-		if err != nil {
-			return nil, err
+// processTemplate fetches the content of a template argument, written
+// as prefix:name[@ref]. When pins is not nil, templates from pinnable
+// sources are fetched at the revision recorded in the lock, and newly
+// resolved revisions are added to it.
+func processTemplate(arg string, pins *lock.Lock) (content []byte, err error) {
+	// Remote templates are written as prefix:name (gh:Go, tt:go, ...),
+	// local templates have no prefix
+	prefix, templateName := "", arg
+	if i := strings.Index(arg, ":"); i >= 0 {
+		prefix = strings.TrimSpace(arg[:i])
+		templateName = strings.TrimSpace(arg[i+1:])
+	}
+
+	// A template can be pinned to a commit SHA, tag or branch with @ref
+	ref := ""
+	if i := strings.LastIndex(templateName, "@"); i >= 0 {
+		templateName, ref = templateName[:i], templateName[i+1:]
+		if ref == "" {
+			return nil, fmt.Errorf("missing ref after @ in %s", arg)
 		}
+	}
 
-		// Fetch available templates
-		templateList, err := templates.FetchTemplates(src.owner, src.repo, src.path, prefix)
-		if err != nil {
-			return nil, fmt.Errorf("unable to fetch templates from %s: %v", prefix, err)
+	src, err := templates.Lookup(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	pinnable, ok := src.(templates.Pinnable)
+	if !ok {
+		if ref != "" {
+			return nil, fmt.Errorf("templates from %s cannot be fetched at a ref", src.DisplayName())
 		}
 
-		// Find the specific template
-		downloadURL, err := findTemplate(templateName, templateList)
+		content, err = src.Fetch(templateName)
 		if err != nil {
+			if prefix == "" {
+				return nil, fmt.Errorf("unable to fetch local template: %v", err)
+			}
 			return nil, err
 		}
+		return content, nil
+	}
 
-		content, err = templates.GetTemplateContent(downloadURL)
-		if err != nil {
-			return nil, fmt.Errorf("unable to fetch content: %v", err)
+	if pins == nil {
+		if ref == "" {
+			return src.Fetch(templateName)
 		}
+		content, _, err := pinnable.Resolve(templateName, ref)
+		return content, err
+	}
 
-		return content, nil
+	if entry, ok := pins.Get(arg); ok {
+		content, err := pinnable.FetchPinned(entry.Pin)
+		if !errors.Is(err, templates.ErrStalePin) {
+			return content, err
+		}
+		utils.PrintWarning(fmt.Sprintf("%s is pinned to %s, which is no longer its source. Resolving it again.", arg, entry.Repo))
 	}
 
-	// Handle local templates
-	content, err = templates.GetLocalTemplate(arg)
+	content, pin, err := pinnable.Resolve(templateName, ref)
+	if err != nil && ref == "" {
+		// The revision cannot be looked up offline or without a
+		// connection, but a cached copy of the template may be available
+		if content, fetchErr := src.Fetch(templateName); fetchErr == nil {
+			utils.PrintWarning(fmt.Sprintf("%s was not pinned: %v", arg, err))
+			return content, nil
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("unable to fetch local template: %v", err)
+		return nil, err
 	}
-
+	pins.Set(lock.Entry{Template: arg, Pin: pin})
 	return content, nil
 }
+
+// savePins writes the lockfile, warning rather than failing since the
+// .gitignore itself has already been written
+func savePins(pins *lock.Lock) {
+	if err := pins.Save(); err != nil {
+		utils.PrintWarning(fmt.Sprintf("Unable to write %s: %v", lock.FileName, err))
+	}
+}
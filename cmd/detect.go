@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jasonuc/gignr/internal/detect"
+	"github.com/jasonuc/gignr/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var detectCmd = &cobra.Command{
+	Use:   "detect",
+	Short: "Suggest templates based on the files in the current project",
+	Long: `The detect command scans the current directory for marker files such as
+go.mod, package.json, pyproject.toml, Cargo.toml, *.csproj, .idea/ and
+.vscode/, and suggests a gignr create command with matching templates.
+
+Use "gignr create --auto" to build the .gitignore from the suggestions
+directly.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		suggestions, err := detectTemplates()
+		if err != nil {
+			utils.PrintError(err.Error())
+			return
+		}
+
+		if len(suggestions) == 0 {
+			utils.PrintAlert("No known project files found, nothing to suggest")
+			return
+		}
+
+		var names []string
+		fmt.Println("Detected templates:")
+		for _, s := range suggestions {
+			fmt.Printf("  %-24s (%s)\n", s.Template, s.Marker)
+			names = append(names, s.Template)
+		}
+
+		fmt.Println()
+		utils.PrintSuccess(fmt.Sprintf("Suggested command: gignr create %s", strings.Join(names, " ")))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(detectCmd)
+}
+
+func detectTemplates() ([]detect.Suggestion, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine the current directory: %v", err)
+	}
+
+	suggestions, err := detect.Scan(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan project: %v", err)
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jasonuc/gignr/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var explainCmd = &cobra.Command{
+	Use:     "explain <path>",
+	Example: "gignr explain debug.log",
+	Args:    cobra.ExactArgs(1),
+	Short:   "Show which .gitignore rule decides whether a path is ignored",
+	Long: `The explain command prints the .gitignore file, line and gignr template
+block of the rule that decides whether a path is ignored.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		m, err := newMatcher()
+		if err != nil {
+			utils.PrintError(err.Error())
+			return
+		}
+
+		arg := args[0]
+		res, err := matchArg(m, arg)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Unable to check %s: %v", arg, err))
+			return
+		}
+
+		if res.Rule == nil {
+			fmt.Printf("%s is not ignored: no rule matches it\n", arg)
+			return
+		}
+
+		state := "not ignored"
+		if res.Ignored {
+			state = "ignored"
+		}
+		fmt.Printf("%s is %s\n", arg, state)
+
+		rel, _ := m.Rel(arg)
+		if res.Path != rel {
+			fmt.Printf("  because its parent directory %s/ is ignored\n", res.Path)
+		}
+
+		rule := res.Rule
+		fmt.Printf("  rule:     %s\n", rule.Pattern)
+		fmt.Printf("  file:     %s:%d\n", rule.File, rule.Line)
+		if rule.Template != "" {
+			fmt.Printf("  template: %s\n", rule.Template)
+		} else {
+			fmt.Printf("  template: none (hand-written rule)\n")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+}
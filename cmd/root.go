@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/fatih/color"
 	cc "github.com/ivanpirog/coloredcobra"
+	"github.com/jasonuc/gignr/internal/cache"
+	"github.com/jasonuc/gignr/internal/httpclient"
+	"github.com/jasonuc/gignr/internal/templates"
 	"github.com/jasonuc/gignr/internal/version"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -33,15 +37,36 @@ func Execute(currentVersion string) error {
 	})
 
 	rootCmd.Version = currentVersion
-	info := version.FetchUpdateInfo(rootCmd.Version)
+	httpclient.SetUserAgent("gignr/" + currentVersion)
+
+	// The update check runs once flags and config are loaded, so it can
+	// be skipped offline
+	var info version.VersionInfo
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		cache.SetOffline(offline)
+		if !cache.Offline() {
+			info = version.FetchUpdateInfo(rootCmd.Version)
+		}
+	}
 	defer info.PromptUpdateIfAvailable()
 	ctx := version.WithContext(context.Background(), &info)
 
-	return rootCmd.ExecuteContext(ctx)
+	err := rootCmd.ExecuteContext(ctx)
+	templates.WaitForRevalidation(revalidateTimeout)
+	return err
 }
 
+// offline is not bound to viper, which would write it into the config
+var offline bool
+
+// revalidateTimeout is how long gignr waits on exit for expired cache
+// entries that are being refreshed in the background
+const revalidateTimeout = 10 * time.Second
+
 func init() {
 	cobra.OnInitialize(initConfig)
+
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Use cached and built-in templates only, without network access")
 }
 
 func initConfig() {
@@ -3,8 +3,6 @@ package cmd
 import (
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 
 	"github.com/jasonuc/gignr/internal/cache"
 	"github.com/jasonuc/gignr/internal/templates"
@@ -20,9 +18,9 @@ var searchCmd = &cobra.Command{
 	Long: `Search and browse .gitignore templates using an interactive TUI.
 Navigate between sources, filter templates, and select them for use.`,
 	PreRun: func(cmd *cobra.Command, args []string) {
-		templates.InitGitHubClient("")
-		ensureDefaultCaches()
+		initSources()
 		refreshCustomRepos()
+		ensureSourceCaches()
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := tui.RunSearch(); err != nil {
@@ -31,54 +29,39 @@ Navigate between sources, filter templates, and select them for use.`,
 	},
 }
 
-func ensureDefaultCaches() {
-	cacheDir := cache.GetCacheDir()
-	defaultSources := map[string]struct {
-		owner string
-		repo  string
-		path  string
-	}{
-		"github.json": {"github", "gitignore", ""},
-		"toptal.json": {"toptal", "gitignore", "templates"},
-	}
-
-	for filename, source := range defaultSources {
-		cachePath := filepath.Join(cacheDir, filename)
-		if _, err := os.Stat(cachePath); os.IsNotExist(err) {
-			fetchAndSaveTemplates(source.owner, source.repo, source.path, "", filename)
+// ensureSourceCaches lists every remote source once so the TUI can be
+// populated from cache
+func ensureSourceCaches() {
+	for _, src := range templates.Sources() {
+		if src.Prefix() == "" {
+			continue
+		}
+		if _, err := src.List(); err != nil {
+			utils.PrintWarning(fmt.Sprintf("Failed to fetch templates from %s: %v", src.DisplayName(), err))
 		}
 	}
 }
 
 func refreshCustomRepos() {
-	if !viper.GetBool("cache_needs_refresh") {
+	// Offline, the refresh is left for the next run with a network
+	if !viper.GetBool("cache_needs_refresh") || cache.Offline() {
 		return
 	}
 
-	repos := viper.GetStringMapString("repositories")
-	for nickname, repoURL := range repos {
-		owner, repo, err := utils.ExtractRepoDetails(repoURL)
-		if err != nil {
-			utils.PrintWarning(fmt.Sprintf("Invalid repository URL format for %s", nickname))
+	for _, src := range templates.Sources() {
+		if templates.IsReservedPrefix(src.Prefix()) {
 			continue
 		}
-
-		cacheFile := fmt.Sprintf("%s.json", nickname)
-		fetchAndSaveTemplates(owner, repo, "", nickname, cacheFile)
+		if refresher, ok := src.(templates.Refresher); ok {
+			if err := refresher.Refresh(); err != nil {
+				utils.PrintWarning(fmt.Sprintf("Failed to fetch templates from %s: %v", src.DisplayName(), err))
+			}
+		}
 	}
 
 	cache.UpdateCacheNeedRefreshStatus(false)
 }
 
-func fetchAndSaveTemplates(owner, repo, path, sourceID, cacheFile string) {
-	fetchedTemplates, err := templates.FetchTemplates(owner, repo, path, sourceID)
-	if err != nil {
-		utils.PrintWarning(fmt.Sprintf("Failed to fetch templates from %s: %v", owner, err))
-		return
-	}
-	templates.SaveTemplatesToCache(cacheFile, fetchedTemplates)
-}
-
 func init() {
 	rootCmd.AddCommand(searchCmd)
 }
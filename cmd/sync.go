@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jasonuc/gignr/internal/gitignore"
+	"github.com/jasonuc/gignr/internal/lock"
+	"github.com/jasonuc/gignr/internal/manifest"
+	"github.com/jasonuc/gignr/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var manifestPath string
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Rebuild the .gitignore file from the project manifest",
+	Long: `The sync command rebuilds the .gitignore file from the templates, rules and
+output path listed in the project manifest (.gignr.yaml):
+
+  templates:
+    - gh:Go
+    - ghg:JetBrains
+  rules:
+    - /dist
+  output: .gitignore
+  dedupe: true
+
+Templates are pinned in gignr.lock next to the manifest, use --upgrade to
+move the pins to the latest revisions. Use "gignr verify" in CI to check that the file is still in sync.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		m, err := manifest.Load(manifestPath)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Unable to load manifest: %v", err))
+			return
+		}
+
+		pins, err := lock.Load(filepath.Join(m.Dir(), lock.FileName))
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Unable to read %s: %v", lock.FileName, err))
+			return
+		}
+		if upgradePins {
+			pins.Retain(nil)
+		}
+
+		file, report, err := buildFromManifest(m, pins)
+		if err != nil {
+			utils.PrintError(err.Error())
+			return
+		}
+		printMergeReport(report, m.Dedupe)
+
+		if err := os.WriteFile(m.OutputPath(), file.Render(), 0644); err != nil {
+			utils.PrintError(fmt.Sprintf("Failed to write %s: %v", m.OutputPath(), err))
+			return
+		}
+
+		pins.Retain(m.Templates)
+		savePins(pins)
+
+		utils.PrintSuccess(fmt.Sprintf("Synced %s from %s", m.OutputPath(), manifestPath))
+	},
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&upgradePins, "upgrade", false, "Fetch the latest template content instead of the revisions pinned in "+lock.FileName)
+	syncCmd.Flags().StringVarP(&manifestPath, "file", "f", manifest.FileName, "Path to the project manifest")
+	rootCmd.AddCommand(syncCmd)
+}
+
+// buildFromManifest renders the file described by the manifest. Unlike
+// create, it fails when any template cannot be processed rather than
+// writing an incomplete file.
+func buildFromManifest(m *manifest.Manifest, pins *lock.Lock) (*gitignore.File, gitignore.MergeReport, error) {
+	initSources()
+
+	file := &gitignore.File{}
+	for _, arg := range m.Templates {
+		content, err := processTemplate(arg, pins)
+		if err != nil {
+			return nil, gitignore.MergeReport{}, fmt.Errorf("error processing %s: %v", arg, err)
+		}
+		file.Set(arg, content)
+	}
+
+	report := mergeFile(file, m.Dedupe)
+
+	if len(m.Rules) > 0 {
+		file.AppendLines(append([]string{"# Project rules from " + manifestName(m)}, m.Rules...)...)
+	}
+
+	return file, report, nil
+}
+
+// manifestName returns the path of the manifest relative to the
+// generated file
+func manifestName(m *manifest.Manifest) string {
+	rel, err := filepath.Rel(filepath.Dir(m.OutputPath()), m.Path())
+	if err != nil {
+		return filepath.Base(m.Path())
+	}
+	return filepath.ToSlash(rel)
+}
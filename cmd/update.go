@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/jasonuc/gignr/internal/gitignore"
+	"github.com/jasonuc/gignr/internal/lock"
+	"github.com/jasonuc/gignr/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var updateCmd = &cobra.Command{
+	Use:     "update [templates]...",
+	Example: "gignr update\ngignr update gh:Go",
+	Short:   "Refresh the gignr-managed blocks of the .gitignore file",
+	Long: `The update command re-fetches the templates of every block between
+"# >>> gignr <template>" and "# <<< gignr <template>" markers and replaces
+the block content. Lines outside the markers are kept as they are.
+
+Pass template names to refresh only those blocks. Pins recorded in
+gignr.lock for the refreshed blocks are moved to the latest revision.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := os.ReadFile(".gitignore")
+		if err != nil {
+			utils.PrintError("No .gitignore file found in the current directory.")
+			return
+		}
+
+		file, err := gitignore.Parse(data)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Unable to parse .gitignore: %v", err))
+			return
+		}
+
+		managed := file.Templates()
+		if len(managed) == 0 {
+			utils.PrintAlert("No gignr-managed blocks found in .gitignore")
+			return
+		}
+
+		for _, arg := range args {
+			if !slices.Contains(managed, arg) {
+				utils.PrintWarning(fmt.Sprintf("%s is not a managed block in .gitignore", arg))
+			}
+		}
+
+		pins, err := lock.Load(lock.FileName)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Unable to read %s: %v", lock.FileName, err))
+			return
+		}
+
+		initSources()
+
+		var updated int
+		for _, name := range managed {
+			if len(args) > 0 && !slices.Contains(args, name) {
+				continue
+			}
+
+			// Updating moves the pin of the block to the latest revision
+			pins.Remove(name)
+			content, err := processTemplate(name, pins)
+			if err != nil {
+				utils.PrintError(fmt.Sprintf("Error processing %s: %v. Keeping the existing block.", name, err))
+				continue
+			}
+
+			file.Set(name, content)
+			updated++
+		}
+
+		if err := os.WriteFile(".gitignore", file.Render(), 0644); err != nil {
+			utils.PrintError(fmt.Sprintf("Failed to write .gitignore file: %v", err))
+			return
+		}
+
+		savePins(pins)
+
+		utils.PrintSuccess(fmt.Sprintf("Updated %d template block(s) in .gitignore", updated))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+}
@@ -6,16 +6,21 @@ import (
 	"os/exec"
 	"regexp"
 
+	"github.com/jasonuc/gignr/internal/cache"
 	"github.com/jasonuc/gignr/internal/utils"
 	"github.com/jasonuc/gignr/internal/version"
 	"github.com/spf13/cobra"
 )
 
 var upgradeCmd = &cobra.Command{
-	Use:     "upgrade",
-	Aliases: []string{"update"},
-	Short:   "Installs the latest version of the CLI.",
+	Use:   "upgrade",
+	Short: "Installs the latest version of the CLI.",
 	Run: func(cmd *cobra.Command, args []string) {
+		if cache.Offline() {
+			utils.PrintError("Cannot upgrade gignr while offline.")
+			return
+		}
+
 		info := version.FromContext(cmd.Context())
 		if !info.IsOutdated {
 			utils.PrintAlert("gignr is already up to date.")
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jasonuc/gignr/internal/lock"
+	"github.com/jasonuc/gignr/internal/manifest"
+	"github.com/jasonuc/gignr/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that the .gitignore file matches the project manifest",
+	Long: `The verify command rebuilds the .gitignore file from the project manifest
+(.gignr.yaml) in memory and compares it with the file on disk. It exits
+with a non-zero status when they differ, so it can be used in CI.
+Templates pinned in gignr.lock are fetched at their pinned revisions.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := manifest.Load(manifestPath)
+		if err != nil {
+			return fmt.Errorf("unable to load manifest: %v", err)
+		}
+
+		pins, err := lock.Load(filepath.Join(m.Dir(), lock.FileName))
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %v", lock.FileName, err)
+		}
+
+		file, _, err := buildFromManifest(m, pins)
+		if err != nil {
+			return err
+		}
+
+		actual, err := os.ReadFile(m.OutputPath())
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %v", m.OutputPath(), err)
+		}
+
+		if !bytes.Equal(actual, file.Render()) {
+			return fmt.Errorf("%s has drifted from %s, run gignr sync to rebuild it", m.OutputPath(), manifestPath)
+		}
+
+		utils.PrintSuccess(fmt.Sprintf("%s is in sync with %s", m.OutputPath(), manifestPath))
+		return nil
+	},
+}
+
+func init() {
+	verifyCmd.Flags().StringVarP(&manifestPath, "file", "f", manifest.FileName, "Path to the project manifest")
+	rootCmd.AddCommand(verifyCmd)
+}
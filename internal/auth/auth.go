@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultHost is the host of public GitHub
+const DefaultHost = "github.com"
+
+// GitHubToken returns the token to use for requests to a GitHub host. It
+// is resolved, in order, from the GITHUB_TOKEN and GH_TOKEN environment
+// variables, the github.token config key and the hosts file of the gh
+// CLI. An empty string means requests are made unauthenticated.
+func GitHubToken(host string) string {
+	if host == "" {
+		host = DefaultHost
+	}
+
+	if host == DefaultHost {
+		for _, env := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+			if token := strings.TrimSpace(os.Getenv(env)); token != "" {
+				return token
+			}
+		}
+		if token := strings.TrimSpace(viper.GetString("github.token")); token != "" {
+			return token
+		}
+	} else {
+		for _, env := range []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+			if token := strings.TrimSpace(os.Getenv(env)); token != "" {
+				return token
+			}
+		}
+	}
+
+	return ghCLIToken(host)
+}
+
+// ForgeToken returns the token for a GitLab, Gitea or Bitbucket server,
+// resolved from the <KIND>_TOKEN environment variable or the <kind>.token
+// config key, e.g. GITLAB_TOKEN and gitlab.token
+func ForgeToken(kind string) string {
+	if token := strings.TrimSpace(os.Getenv(strings.ToUpper(kind) + "_TOKEN")); token != "" {
+		return token
+	}
+	return strings.TrimSpace(viper.GetString(kind + ".token"))
+}
+
+type ghHost struct {
+	OAuthToken string `yaml:"oauth_token"`
+	User       string `yaml:"user"`
+	Users      map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	} `yaml:"users"`
+}
+
+// ghCLIToken reads the token the gh CLI stored for host in its hosts
+// file. Tokens kept in the system keyring are not available here.
+func ghCLIToken(host string) string {
+	data, err := os.ReadFile(filepath.Join(ghConfigDir(), "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+
+	var hosts map[string]ghHost
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return ""
+	}
+
+	entry, ok := hosts[host]
+	if !ok {
+		return ""
+	}
+	if entry.OAuthToken != "" {
+		return entry.OAuthToken
+	}
+	// Newer gh versions keep a token per account
+	return entry.Users[entry.User].OAuthToken
+}
+
+func ghConfigDir() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh")
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("AppData"); dir != "" {
+			return filepath.Join(dir, "GitHub CLI")
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gh")
+}
@@ -1,18 +1,55 @@
 package cache
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"time"
 
 	"github.com/jasonuc/gignr/internal/utils"
 	"github.com/spf13/viper"
 )
 
+// TTL is how long cached data is used without asking the server. Expired
+// data is revalidated with a conditional request rather than downloaded
+// again, so it can be kept short.
+const TTL = 24 * time.Hour
+
 type TemplateContentCache struct {
 	Updated time.Time `json:"updated"`
 	Content string    `json:"content"`
+	Validators
+}
+
+// Validators are the response headers used to revalidate cached data
+// with a conditional request
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ValidatorsFrom reads the validators of a response
+func ValidatorsFrom(resp *http.Response) Validators {
+	return Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// Apply makes req conditional, so an unchanged resource is answered with
+// 304 Not Modified
+func (v Validators) Apply(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
 }
 
 func GetCacheDir() string {
@@ -33,27 +70,150 @@ func GetCacheDir() string {
 	return cacheDir
 }
 
+// ErrCorrupt is returned by LoadCache for a cache file that cannot be
+// decoded. The file is moved aside so it is rebuilt on the next save.
+var ErrCorrupt = errors.New("corrupted cache file")
+
 func LoadCache(fileName string, target interface{}) error {
 	cachePath := filepath.Join(GetCacheDir(), fileName)
 	data, err := os.ReadFile(cachePath)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(data, target)
+	if err := decode(data, target); err != nil {
+		// Another process may replace the file meanwhile, so it is only
+		// moved aside while it still holds the same data
+		unlock := lock(fileName)
+		defer unlock()
+		if current, err := os.ReadFile(cachePath); err == nil && bytes.Equal(current, data) {
+			quarantine(cachePath)
+		}
+		return fmt.Errorf("%w %s: %v", ErrCorrupt, fileName, err)
+	}
+	return nil
 }
 
+// SaveCache writes a cache file atomically, so other gignr processes
+// never read a partially written file
 func SaveCache(fileName string, data interface{}) error {
-	cachePath := filepath.Join(GetCacheDir(), fileName)
 	content, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(cachePath, content, 0644)
+	return writeFileAtomic(filepath.Join(GetCacheDir(), fileName), content)
+}
+
+// UpdateCache loads a cache file into target, lets modify change it and
+// saves it, holding an advisory lock on the file throughout so that
+// concurrent gignr processes do not lose each other's changes. A missing
+// or corrupted file starts out as the zero value of target. Nothing is
+// saved when modify returns false.
+func UpdateCache(fileName string, target interface{}, modify func() bool) error {
+	unlock := lock(fileName)
+	defer unlock()
+
+	cachePath := filepath.Join(GetCacheDir(), fileName)
+	data, err := os.ReadFile(cachePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := decode(data, target); err != nil {
+			quarantine(cachePath)
+		}
+	}
+
+	if !modify() {
+		return nil
+	}
+	return SaveCache(fileName, target)
+}
+
+// decode unmarshals data into target, leaving target empty on failure.
+// Maps are left empty rather than nil, so entries can be added.
+func decode(data []byte, target interface{}) error {
+	if err := json.Unmarshal(data, target); err != nil {
+		v := reflect.ValueOf(target).Elem()
+		if v.Kind() == reflect.Map {
+			v.Set(reflect.MakeMap(v.Type()))
+		} else {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return err
+	}
+	return nil
+}
+
+// quarantine moves a corrupted cache file aside, keeping the latest one
+// for inspection
+func quarantine(cachePath string) {
+	corrupt := cachePath + ".corrupt"
+	if err := os.Rename(cachePath, corrupt); err == nil {
+		utils.PrintWarning(fmt.Sprintf("Cache file %s was corrupted and has been moved to %s. It will be rebuilt.", filepath.Base(cachePath), filepath.Base(corrupt)))
+	}
+}
+
+// lock takes an advisory lock on a cache file, waiting while another
+// gignr process holds it, and returns the function releasing it. Where
+// locks are unavailable, e.g. on some network file systems, the file is
+// used unlocked.
+func lock(fileName string) (unlock func()) {
+	lockPath := filepath.Join(GetCacheDir(), "."+fileName+".lock")
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return func() {}
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return func() {}
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}
+}
+
+// writeFileAtomic writes a temporary file next to path and renames it
+// into place
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
 
-// IsCacheExpired checks if a cache entry is expired (TTL: 2 weeks)
+// IsCacheExpired checks if a cache entry is older than TTL
 func IsCacheExpired(updatedTime time.Time) bool {
-	return time.Since(updatedTime) > 14*24*time.Hour
+	return time.Since(updatedTime) > TTL
+}
+
+// offlineFlag is set from --offline. It is kept out of viper so that
+// writing the config does not persist it.
+var offlineFlag bool
+
+// SetOffline records whether --offline was given
+func SetOffline(offline bool) {
+	offlineFlag = offline
+}
+
+// Offline reports whether gignr was asked to work without a network,
+// with --offline or `offline: true` in the config
+func Offline() bool {
+	return offlineFlag || viper.GetBool("offline")
 }
 
 func UpdateCacheNeedRefreshStatus(newStatus bool) {
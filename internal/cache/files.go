@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File describes a file or directory in the cache directory
+type File struct {
+	Name string
+	// Updated is when the newest entry was stored, or the modification
+	// time when entries carry no timestamp
+	Updated time.Time
+	// Entries counts templates in listings, content entries and
+	// directories holding clones or unpacked archives
+	Entries int
+	Size    int64
+	IsDir   bool
+}
+
+// Files describes everything in the cache directory, including
+// quarantined files, sorted by name
+func Files() ([]File, error) {
+	dir := GetCacheDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for _, entry := range entries {
+		// Skip lock files and files being written
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		file := File{Name: entry.Name(), Updated: info.ModTime(), IsDir: entry.IsDir()}
+		p := filepath.Join(dir, entry.Name())
+
+		if entry.IsDir() {
+			children, _ := os.ReadDir(p)
+			file.Entries = len(children)
+			file.Size = dirSize(p)
+		} else {
+			file.Size = info.Size()
+			if data, err := os.ReadFile(p); err == nil {
+				if updated, n, ok := describe(data); ok {
+					file.Entries = n
+					if !updated.IsZero() {
+						file.Updated = updated
+					}
+				}
+			}
+		}
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// describe reads the timestamp and entry count of a cache file, which is
+// either a listing with "updated" and "templates" keys or a map of
+// entries that each have an "updated" key
+func describe(data []byte) (time.Time, int, bool) {
+	var listing struct {
+		Updated   time.Time         `json:"updated"`
+		Templates []json.RawMessage `json:"templates"`
+	}
+	if err := json.Unmarshal(data, &listing); err == nil && listing.Templates != nil {
+		return listing.Updated, len(listing.Templates), true
+	}
+
+	var entries map[string]struct {
+		Updated time.Time `json:"updated"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return time.Time{}, 0, false
+	}
+
+	var newest time.Time
+	for _, entry := range entries {
+		if entry.Updated.After(newest) {
+			newest = entry.Updated
+		}
+	}
+	return newest, len(entries), true
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// Clear removes everything in the cache directory
+func Clear() error {
+	dir := GetCacheDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package detect
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// maxDepth limits how far below the root markers are searched for, so
+// large trees are scanned quickly and nested example projects are less
+// likely to produce suggestions
+const maxDepth = 3
+
+type marker struct {
+	// pattern is matched against file or directory names with
+	// filepath.Match
+	pattern  string
+	dir      bool
+	template string
+}
+
+var markers = []marker{
+	{pattern: "go.mod", template: "gh:Go"},
+	{pattern: "go.work", template: "gh:Go"},
+	{pattern: "package.json", template: "gh:Node"},
+	{pattern: "pyproject.toml", template: "gh:Python"},
+	{pattern: "requirements.txt", template: "gh:Python"},
+	{pattern: "setup.py", template: "gh:Python"},
+	{pattern: "Pipfile", template: "gh:Python"},
+	{pattern: "Cargo.toml", template: "gh:Rust"},
+	{pattern: "*.csproj", template: "gh:VisualStudio"},
+	{pattern: "*.fsproj", template: "gh:VisualStudio"},
+	{pattern: "*.sln", template: "gh:VisualStudio"},
+	{pattern: "pom.xml", template: "gh:Maven"},
+	{pattern: "build.gradle", template: "gh:Gradle"},
+	{pattern: "build.gradle.kts", template: "gh:Gradle"},
+	{pattern: "Gemfile", template: "gh:Ruby"},
+	{pattern: "composer.json", template: "gh:Composer"},
+	{pattern: "pubspec.yaml", template: "gh:Dart"},
+	{pattern: "CMakeLists.txt", template: "gh:CMake"},
+	{pattern: "*.tf", template: "gh:Terraform"},
+	{pattern: "mix.exs", template: "gh:Elixir"},
+	{pattern: "stack.yaml", template: "gh:Haskell"},
+	{pattern: "*.cabal", template: "gh:Haskell"},
+	{pattern: "Package.swift", template: "gh:Swift"},
+	{pattern: "build.sbt", template: "gh:Scala"},
+	{pattern: "Podfile", template: "tt:cocoapods"},
+	{pattern: "next.config.*", template: "tt:nextjs"},
+	{pattern: "*.xcodeproj", dir: true, template: "ghg:Xcode"},
+	{pattern: ".idea", dir: true, template: "ghg:JetBrains"},
+	{pattern: ".vscode", dir: true, template: "ghg:VisualStudioCode"},
+	{pattern: ".DS_Store", template: "ghg:macOS"},
+}
+
+// skipDirs are never descended into, they hold dependencies or tool
+// state rather than the project itself
+var skipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	".venv":        true,
+	"venv":         true,
+	"target":       true,
+}
+
+// Suggestion is a template proposed for the project
+type Suggestion struct {
+	Template string
+	// Marker is the path, relative to the root, of the file or directory
+	// that triggered the suggestion
+	Marker string
+}
+
+// Scan walks the tree at root and suggests templates based on the
+// marker files it finds. Each template is suggested once, in the order
+// its first marker was found.
+func Scan(root string) ([]Suggestion, error) {
+	var suggestions []Suggestion
+	seen := make(map[string]bool)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries do not tell us anything about the project
+			if d != nil && d.IsDir() && path != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+
+		for _, m := range markers {
+			if m.dir != d.IsDir() || seen[m.template] {
+				continue
+			}
+			if ok, _ := filepath.Match(m.pattern, d.Name()); ok {
+				seen[m.template] = true
+				marker := filepath.ToSlash(rel)
+				if d.IsDir() {
+					marker += "/"
+				}
+				suggestions = append(suggestions, Suggestion{Template: m.template, Marker: marker})
+			}
+		}
+
+		if d.IsDir() {
+			depth := strings.Count(filepath.ToSlash(rel), "/") + 1
+			if skipDirs[d.Name()] || depth >= maxDepth || isMarkerDir(d.Name()) {
+				return fs.SkipDir
+			}
+		}
+		return nil
+	})
+
+	return suggestions, err
+}
+
+// isMarkerDir reports whether a directory is itself a marker, its
+// contents are tool state and are not scanned
+func isMarkerDir(name string) bool {
+	for _, m := range markers {
+		if ok, _ := filepath.Match(m.pattern, name); ok && m.dir {
+			return true
+		}
+	}
+	return false
+}
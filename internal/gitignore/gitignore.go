@@ -0,0 +1,196 @@
+package gitignore
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	beginMarker = "# >>> gignr "
+	endMarker   = "# <<< gignr "
+)
+
+// Section is a run of lines in a .gitignore file. Managed sections are
+// enclosed in gignr markers and owned by a template, all other lines
+// belong to the user and are never rewritten.
+type Section struct {
+	Template string
+	Lines    []string
+}
+
+// Managed reports whether the section is a template block
+func (s Section) Managed() bool {
+	return s.Template != ""
+}
+
+// File is a parsed .gitignore file
+type File struct {
+	Sections []Section
+}
+
+// Parse splits a .gitignore file into managed blocks and user lines
+func Parse(data []byte) (*File, error) {
+	f := &File{}
+	if len(data) == 0 {
+		return f, nil
+	}
+
+	lines := splitLines(string(data))
+
+	var text []string
+	for i := 0; i < len(lines); i++ {
+		name, ok := parseMarker(lines[i], beginMarker)
+		if !ok {
+			if _, ok := parseMarker(lines[i], endMarker); ok {
+				return nil, fmt.Errorf("line %d: end marker without a matching begin marker", i+1)
+			}
+			text = append(text, lines[i])
+			continue
+		}
+
+		if len(text) > 0 {
+			f.Sections = append(f.Sections, Section{Lines: text})
+			text = nil
+		}
+
+		block := Section{Template: name}
+		closed := false
+		for i++; i < len(lines); i++ {
+			if _, ok := parseMarker(lines[i], beginMarker); ok {
+				return nil, fmt.Errorf("line %d: block %s is not closed before the next block", i+1, name)
+			}
+			if end, ok := parseMarker(lines[i], endMarker); ok {
+				if end != name {
+					return nil, fmt.Errorf("line %d: block %s closed by end marker for %s", i+1, name, end)
+				}
+				closed = true
+				break
+			}
+			block.Lines = append(block.Lines, lines[i])
+		}
+		if !closed {
+			return nil, fmt.Errorf("block %s is missing its end marker", name)
+		}
+		f.Sections = append(f.Sections, block)
+	}
+
+	if len(text) > 0 {
+		f.Sections = append(f.Sections, Section{Lines: text})
+	}
+
+	return f, nil
+}
+
+// Render serialises the file, wrapping managed sections in markers
+func (f *File) Render() []byte {
+	var b strings.Builder
+	for _, s := range f.Sections {
+		if s.Managed() {
+			b.WriteString(beginMarker + s.Template + "\n")
+		}
+		for _, line := range s.Lines {
+			b.WriteString(line + "\n")
+		}
+		if s.Managed() {
+			b.WriteString(endMarker + s.Template + "\n")
+		}
+	}
+	return []byte(b.String())
+}
+
+// Templates returns the templates of all managed blocks in file order
+func (f *File) Templates() []string {
+	var names []string
+	for _, s := range f.Sections {
+		if s.Managed() {
+			names = append(names, s.Template)
+		}
+	}
+	return names
+}
+
+// Has reports whether the file contains a managed block for template
+func (f *File) Has(template string) bool {
+	return f.find(template) >= 0
+}
+
+// Set replaces the content of the managed block for template, or
+// appends a new block at the end of the file if there is none. Marker
+// lines in content, such as those of a saved .gitignore, are dropped so
+// blocks never nest.
+func (f *File) Set(template string, content []byte) {
+	var lines []string
+	for _, line := range splitLines(string(content)) {
+		if isMarker(line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	lines = trimTrailingBlank(lines)
+
+	if i := f.find(template); i >= 0 {
+		f.Sections[i].Lines = lines
+		return
+	}
+
+	f.separate()
+	f.Sections = append(f.Sections, Section{Template: template, Lines: lines})
+}
+
+// AppendLines adds user lines at the end of the file
+func (f *File) AppendLines(lines ...string) {
+	f.separate()
+	f.Sections = append(f.Sections, Section{Lines: lines})
+}
+
+// separate keeps a blank line between appended content and whatever
+// precedes it
+func (f *File) separate() {
+	if n := len(f.Sections); n > 0 {
+		last := f.Sections[n-1]
+		if last.Managed() || len(last.Lines) == 0 || strings.TrimSpace(last.Lines[len(last.Lines)-1]) != "" {
+			f.Sections = append(f.Sections, Section{Lines: []string{""}})
+		}
+	}
+}
+
+func (f *File) find(template string) int {
+	for i, s := range f.Sections {
+		if s.Managed() && s.Template == template {
+			return i
+		}
+	}
+	return -1
+}
+
+func parseMarker(line, marker string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, marker) {
+		return "", false
+	}
+
+	name := strings.TrimSpace(strings.TrimPrefix(line, marker))
+	return name, name != ""
+}
+
+func isMarker(line string) bool {
+	_, begin := parseMarker(line, beginMarker)
+	_, end := parseMarker(line, endMarker)
+	return begin || end
+}
+
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func trimTrailingBlank(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,103 @@
+package gitignore
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRender(t *testing.T) {
+	data := `# my rules
+.env
+
+# >>> gignr gh:Go
+bin/
+*.test
+# <<< gignr gh:Go
+
+# >>> gignr tt:macos
+# <<< gignr tt:macos
+local/
+`
+
+	f, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Section{
+		{Lines: []string{"# my rules", ".env", ""}},
+		{Template: "gh:Go", Lines: []string{"bin/", "*.test"}},
+		{Lines: []string{""}},
+		{Template: "tt:macos"},
+		{Lines: []string{"local/"}},
+	}
+	if !reflect.DeepEqual(f.Sections, want) {
+		t.Errorf("Parse = %+v, want %+v", f.Sections, want)
+	}
+	if got := f.Templates(); !reflect.DeepEqual(got, []string{"gh:Go", "tt:macos"}) {
+		t.Errorf("Templates = %v", got)
+	}
+	if got := string(f.Render()); got != data {
+		t.Errorf("Render = %q, want %q", got, data)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"# >>> gignr gh:Go\nbin/\n", "missing its end marker"},
+		{"bin/\n# <<< gignr gh:Go\n", "end marker without a matching begin marker"},
+		{"# >>> gignr gh:Go\n# >>> gignr tt:go\n", "not closed before the next block"},
+		{"# >>> gignr gh:Go\n# <<< gignr tt:go\n", "closed by end marker for tt:go"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.data)); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q) = %v, want an error containing %q", tt.data, err, tt.err)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	f, err := Parse([]byte(".env\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Set("gh:Go", []byte("bin/\r\n*.test\n\n\n"))
+	f.AppendLines("local/")
+	f.Set("gh:Go", []byte("bin/\n"))
+
+	want := ".env\n\n# >>> gignr gh:Go\nbin/\n# <<< gignr gh:Go\n\nlocal/\n"
+	if got := string(f.Render()); got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+	if !f.Has("gh:Go") || f.Has("tt:go") {
+		t.Errorf("Has reports the wrong blocks for %v", f.Templates())
+	}
+}
+
+func TestSetDropsMarkers(t *testing.T) {
+	// The content of a saved .gitignore still carries its own blocks
+	saved := ".env\n# >>> gignr gh:Go\nbin/\n# <<< gignr gh:Go\n"
+
+	f := &File{}
+	f.Set("local:mine", []byte(saved))
+
+	rendered := f.Render()
+	parsed, err := Parse(rendered)
+	if err != nil {
+		t.Fatalf("Parse(Render()) failed: %v\n%s", err, rendered)
+	}
+	if !reflect.DeepEqual(parsed.Sections, f.Sections) {
+		t.Errorf("Parse(Render()) = %+v, want %+v", parsed.Sections, f.Sections)
+	}
+
+	want := []Section{{Template: "local:mine", Lines: []string{".env", "bin/"}}}
+	if !reflect.DeepEqual(f.Sections, want) {
+		t.Errorf("Set = %+v, want %+v", f.Sections, want)
+	}
+}
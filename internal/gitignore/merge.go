@@ -0,0 +1,208 @@
+package gitignore
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Duplicate is a pattern repeated in a managed block after it was
+// already set, with the same meaning, by an earlier line
+type Duplicate struct {
+	Pattern  string
+	Template string
+	First    string
+}
+
+// Conflict is a pattern re-included with "!" in one template and then
+// ignored again by a later template, either by the same pattern or by a
+// broader one such as a parent directory, undoing the negation
+type Conflict struct {
+	Pattern  string
+	Negated  string
+	Template string
+	Rule     string
+}
+
+// MergeReport summarises how the managed blocks of a file interact
+type MergeReport struct {
+	Duplicates []Duplicate
+	Conflicts  []Conflict
+}
+
+// Analyze finds duplicate patterns and negation conflicts across the
+// managed blocks of the file without modifying it
+func (f *File) Analyze() MergeReport {
+	report, _ := f.analyze()
+	return report
+}
+
+// Dedupe replaces duplicate patterns in managed blocks with a comment
+// pointing at the block that already provides them. A repeated pattern
+// is only removed when no rule of the opposite kind that could match the
+// same paths sits between the two copies, so the result ignores exactly
+// the same paths.
+func (f *File) Dedupe() MergeReport {
+	report, positions := f.analyze()
+	for i, pos := range positions {
+		dup := report.Duplicates[i]
+		f.Sections[pos.section].Lines[pos.line] = fmt.Sprintf("# gignr: removed duplicate %q (already in %s)", dup.Pattern, dup.First)
+	}
+	return report
+}
+
+type position struct {
+	section int
+	line    int
+}
+
+// occurrence is a rule of a managed block, numbered in file order
+type occurrence struct {
+	index    int
+	pattern  string
+	template string
+}
+
+func (f *File) analyze() (MergeReport, []position) {
+	var report MergeReport
+	var duplicates []position
+
+	last := make(map[string]occurrence)
+	// rules in file order, and the negations not yet undone by a later
+	// ignore rule
+	var rules, negations []occurrence
+
+	index := 0
+	for si, s := range f.Sections {
+		if !s.Managed() {
+			continue
+		}
+
+		for li, line := range s.Lines {
+			pattern, ok := normalizePattern(line)
+			if !ok {
+				continue
+			}
+			index++
+			negated := strings.HasPrefix(pattern, "!")
+
+			if !negated {
+				active := negations[:0]
+				for _, n := range negations {
+					if !covers(pattern, strings.TrimPrefix(n.pattern, "!")) {
+						active = append(active, n)
+						continue
+					}
+					if n.template != s.Template {
+						report.Conflicts = append(report.Conflicts, Conflict{
+							Pattern:  strings.TrimPrefix(n.pattern, "!"),
+							Negated:  n.template,
+							Template: s.Template,
+							Rule:     pattern,
+						})
+					}
+				}
+				negations = active
+			}
+
+			if prev, seen := last[pattern]; seen && !opposedSince(rules, prev.index, pattern) {
+				report.Duplicates = append(report.Duplicates, Duplicate{
+					Pattern:  pattern,
+					Template: s.Template,
+					First:    prev.template,
+				})
+				duplicates = append(duplicates, position{si, li})
+				continue
+			}
+
+			o := occurrence{index: index, pattern: pattern, template: s.Template}
+			last[pattern] = o
+			rules = append(rules, o)
+			if negated {
+				negations = append(negations, o)
+			}
+		}
+	}
+
+	return report, duplicates
+}
+
+// opposedSince reports whether a rule of the opposite kind that could
+// match the same paths as pattern follows the rule at index since
+func opposedSince(rules []occurrence, since int, pattern string) bool {
+	negated := strings.HasPrefix(pattern, "!")
+	for i := len(rules) - 1; i >= 0 && rules[i].index > since; i-- {
+		other := rules[i].pattern
+		if strings.HasPrefix(other, "!") != negated && overlaps(pattern, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePattern returns the rule on a line with insignificant
+// whitespace removed, or false for blank lines and comments
+func normalizePattern(line string) (string, bool) {
+	trimmed := strings.TrimRight(line, " \t\r")
+	if strings.HasSuffix(trimmed, "\\") && len(trimmed) < len(line) {
+		// "\ " keeps an escaped trailing space
+		trimmed += " "
+	}
+
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", false
+	}
+	return trimmed, true
+}
+
+// rulePath returns the path part of a rule without its negation, slashes
+// and leading "**/", and whether it is anchored to the .gitignore
+func rulePath(pattern string) (string, bool) {
+	p := strings.TrimSuffix(strings.TrimPrefix(pattern, "!"), "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if rest, ok := strings.CutPrefix(p, "**/"); ok && !strings.Contains(rest, "/") {
+		p, anchored = rest, false
+	}
+	return p, anchored
+}
+
+// covers reports whether ignoring pattern ignores target, or one of the
+// directories it sits in, so that a negation of target has no effect
+func covers(pattern, target string) bool {
+	p, anchored := rulePath(pattern)
+	t, _ := rulePath(target)
+	if strings.Contains(p, "**") {
+		return true
+	}
+
+	parts := strings.Split(t, "/")
+	for i := range parts {
+		candidate := parts[i]
+		if anchored {
+			candidate = strings.Join(parts[:i+1], "/")
+		}
+		if p == candidate {
+			return true
+		}
+		if matched, _ := path.Match(p, candidate); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether two rules could match the same path. Globs
+// that are not matched against each other are assumed to overlap.
+func overlaps(a, b string) bool {
+	if covers(a, b) || covers(b, a) {
+		return true
+	}
+	pa, _ := rulePath(a)
+	pb, _ := rulePath(b)
+	return hasGlob(pa) && hasGlob(pb)
+}
+
+func hasGlob(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
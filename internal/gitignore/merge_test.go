@@ -0,0 +1,135 @@
+package gitignore
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// blocks builds a file with a managed block for each template, given as
+// name followed by its lines
+func blocks(templates ...[]string) *File {
+	f := &File{}
+	for _, t := range templates {
+		f.Set(t[0], []byte(strings.Join(t[1:], "\n")))
+	}
+	return f
+}
+
+func TestAnalyzeConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		file *File
+		want []Conflict
+	}{
+		{
+			name: "same pattern",
+			file: blocks([]string{"a", "!keep.log"}, []string{"b", "keep.log"}),
+			want: []Conflict{{Pattern: "keep.log", Negated: "a", Template: "b", Rule: "keep.log"}},
+		},
+		{
+			name: "parent directory",
+			file: blocks([]string{"a", ".vscode/*", "!.vscode/settings.json"}, []string{"b", ".vscode/"}),
+			want: []Conflict{{Pattern: ".vscode/settings.json", Negated: "a", Template: "b", Rule: ".vscode/"}},
+		},
+		{
+			name: "anchored parent directory",
+			file: blocks([]string{"a", "!/config/app/local.yaml"}, []string{"b", "/config"}),
+			want: []Conflict{{Pattern: "/config/app/local.yaml", Negated: "a", Template: "b", Rule: "/config"}},
+		},
+		{
+			name: "broader glob",
+			file: blocks([]string{"a", "!keep.log"}, []string{"b", "*.log"}),
+			want: []Conflict{{Pattern: "keep.log", Negated: "a", Template: "b", Rule: "*.log"}},
+		},
+		{
+			name: "ignored before the negation",
+			file: blocks([]string{"a", ".vscode/"}, []string{"b", "!.vscode/settings.json"}),
+		},
+		{
+			name: "unrelated directory",
+			file: blocks([]string{"a", "!.vscode/settings.json"}, []string{"b", ".idea/", "settings"}),
+		},
+		{
+			name: "anchored pattern elsewhere",
+			file: blocks([]string{"a", "!app/config/local.yaml"}, []string{"b", "/config"}),
+		},
+		{
+			name: "within one template",
+			file: blocks([]string{"a", "!keep.log", "*.log"}, []string{"b", "bin/"}),
+		},
+		{
+			name: "reported once",
+			file: blocks([]string{"a", "!keep.log"}, []string{"b", "*.log"}, []string{"c", "keep.log"}),
+			want: []Conflict{{Pattern: "keep.log", Negated: "a", Template: "b", Rule: "*.log"}},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.file.Analyze().Conflicts; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Conflicts = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	tests := []struct {
+		name string
+		file *File
+		// removed are the duplicates removed, as template:pattern
+		removed []string
+	}{
+		{
+			name:    "repeated pattern",
+			file:    blocks([]string{"a", "*.log", "bin/"}, []string{"b", "bin/ ", "*.log"}),
+			removed: []string{"b:bin/", "b:*.log"},
+		},
+		{
+			name:    "unrelated negation in between",
+			file:    blocks([]string{"a", "bin/"}, []string{"b", "!.vscode/settings.json"}, []string{"c", "bin/"}),
+			removed: []string{"c:bin/"},
+		},
+		{
+			name: "negation of the same pattern in between",
+			file: blocks([]string{"a", "bin/"}, []string{"b", "!bin/"}, []string{"c", "bin/"}),
+		},
+		{
+			name: "overlapping negation in between",
+			file: blocks([]string{"a", "*.log"}, []string{"b", "!keep.log"}, []string{"c", "*.log"}),
+		},
+		{
+			name: "negated child of a directory in between",
+			file: blocks([]string{"a", ".vscode/"}, []string{"b", "!.vscode/settings.json"}, []string{"c", ".vscode/"}),
+		},
+		{
+			name: "overlapping ignore between negations",
+			file: blocks([]string{"a", "!keep.log"}, []string{"b", "*.log"}, []string{"c", "!keep.log"}),
+		},
+		{
+			name: "user lines are not deduplicated",
+			file: &File{Sections: []Section{{Lines: []string{"bin/"}}, {Template: "a", Lines: []string{"bin/"}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		before := string(tt.file.Render())
+		report := tt.file.Dedupe()
+
+		var removed []string
+		for _, d := range report.Duplicates {
+			removed = append(removed, d.Template+":"+d.Pattern)
+		}
+		if !reflect.DeepEqual(removed, tt.removed) {
+			t.Errorf("%s: removed %v, want %v", tt.name, removed, tt.removed)
+		}
+		if len(tt.removed) == 0 && string(tt.file.Render()) != before {
+			t.Errorf("%s: file changed without duplicates:\n%s", tt.name, tt.file.Render())
+		}
+	}
+
+	f := blocks([]string{"a", "bin/"}, []string{"b", "bin/"})
+	f.Dedupe()
+	if got, want := f.Sections[2].Lines, []string{`# gignr: removed duplicate "bin/" (already in a)`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("deduplicated block = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,222 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	baseBackoff       = 500 * time.Millisecond
+	// maxWait is the longest we sleep for a Retry-After header before
+	// giving up and reporting the limit instead
+	maxWait = time.Minute
+)
+
+var userAgent = "gignr"
+
+// SetUserAgent sets the User-Agent sent with every request
+func SetUserAgent(ua string) {
+	userAgent = ua
+}
+
+// UserAgent returns the User-Agent sent with every request
+func UserAgent() string {
+	return userAgent
+}
+
+// Default is the shared client for unauthenticated requests
+var Default = New()
+
+type config struct {
+	timeout    time.Duration
+	maxRetries int
+	wrap       func(http.RoundTripper) http.RoundTripper
+}
+
+// Option configures a client created with New
+type Option func(*config)
+
+// WithTimeout sets the overall timeout of a request, including retries
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) { c.timeout = d }
+}
+
+// WithRetries sets how many times a failed request is retried
+func WithRetries(n int) Option {
+	return func(c *config) { c.maxRetries = n }
+}
+
+// WithTransport wraps the base transport, e.g. to add authentication.
+// The wrapper runs on every attempt.
+func WithTransport(wrap func(http.RoundTripper) http.RoundTripper) Option {
+	return func(c *config) { c.wrap = wrap }
+}
+
+// New creates a client with timeouts, proxy support from the environment
+// and retries with exponential backoff on 5xx and 429 responses
+func New(opts ...Option) *http.Client {
+	cfg := config{timeout: defaultTimeout, maxRetries: defaultMaxRetries}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var rt http.RoundTripper = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 20 * time.Second,
+	}
+	if cfg.wrap != nil {
+		rt = cfg.wrap(rt)
+	}
+
+	return &http.Client{
+		Timeout:   cfg.timeout,
+		Transport: &retryTransport{base: rt, maxRetries: cfg.maxRetries},
+	}
+}
+
+// RateLimitError is returned when a server reports that the request
+// quota is exhausted
+type RateLimitError struct {
+	Host  string
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return fmt.Sprintf("rate limit of %s exceeded, try again later", e.Host)
+	}
+	wait := time.Until(e.Reset).Round(time.Second)
+	return fmt.Sprintf("rate limit of %s exceeded until %s (in %s). Set GITHUB_TOKEN or run gh auth login to raise the limit",
+		e.Host, e.Reset.Local().Format("15:04:05"), wait)
+}
+
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", userAgent)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, errors.New("request body cannot be replayed for a retry")
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+
+		wait, retry := t.backoff(attempt), attempt < t.maxRetries
+		switch {
+		case err != nil:
+			retry = retry && isTransient(err)
+		case isRateLimited(resp):
+			reset := rateLimitReset(resp)
+			resp.Body.Close()
+			return nil, &RateLimitError{Host: req.URL.Host, Reset: reset}
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			if after, ok := retryAfter(resp); ok {
+				if after > maxWait {
+					resp.Body.Close()
+					return nil, &RateLimitError{Host: req.URL.Host, Reset: time.Now().Add(after)}
+				}
+				wait = after
+			}
+		default:
+			return resp, nil
+		}
+
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt, doubling each time
+// with some jitter
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := baseBackoff << attempt
+	return d + time.Duration(rand.Int64N(int64(d/2)+1))
+}
+
+// isTransient reports whether a transport error may go away on retry.
+// DNS failures usually mean there is no network at all.
+func isTransient(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return false
+}
+
+// isRateLimited reports whether the response says the primary quota is
+// used up, as GitHub does with a 403 or 429 and X-RateLimit-Remaining: 0
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	return resp.Header.Get("X-RateLimit-Remaining") == "0"
+}
+
+func rateLimitReset(resp *http.Response) time.Time {
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		return time.Unix(reset, 0)
+	}
+	if after, ok := retryAfter(resp); ok {
+		return time.Now().Add(after)
+	}
+	return time.Time{}
+}
+
+// retryAfter parses the Retry-After header, given in seconds or as an
+// HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at), true
+	}
+	return 0, false
+}
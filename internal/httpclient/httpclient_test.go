@@ -0,0 +1,181 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// respond serves the responses in order, repeating the last one, and
+// counts the requests
+func respond(t *testing.T, responses ...func(w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		responses[min(n, len(responses))-1](w)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func status(code int, headers ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Set(headers[i], headers[i+1])
+		}
+		w.WriteHeader(code)
+		io.WriteString(w, http.StatusText(code))
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name      string
+		responses []func(w http.ResponseWriter)
+		status    int
+		requests  int32
+		// rateLimited is set when a RateLimitError is expected, along
+		// with its reset time when known
+		rateLimited bool
+		reset       time.Time
+	}{
+		{
+			name:      "success",
+			responses: []func(w http.ResponseWriter){status(http.StatusOK)},
+			status:    http.StatusOK,
+			requests:  1,
+		},
+		{
+			name: "server errors are retried",
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusServiceUnavailable, "Retry-After", "0"),
+				status(http.StatusBadGateway, "Retry-After", "0"),
+				status(http.StatusOK),
+			},
+			status:   http.StatusOK,
+			requests: 3,
+		},
+		{
+			name:      "too many requests is retried until the retries run out",
+			responses: []func(w http.ResponseWriter){status(http.StatusTooManyRequests, "Retry-After", "0")},
+			status:    http.StatusTooManyRequests,
+			requests:  3,
+		},
+		{
+			name:      "client errors are not retried",
+			responses: []func(w http.ResponseWriter){status(http.StatusNotFound)},
+			status:    http.StatusNotFound,
+			requests:  1,
+		},
+		{
+			name: "exhausted quota",
+			responses: []func(w http.ResponseWriter){status(http.StatusForbidden,
+				"X-RateLimit-Remaining", "0", "X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))},
+			requests:    1,
+			rateLimited: true,
+			reset:       reset,
+		},
+		{
+			name:        "exhausted quota with too many requests",
+			responses:   []func(w http.ResponseWriter){status(http.StatusTooManyRequests, "X-RateLimit-Remaining", "0")},
+			requests:    1,
+			rateLimited: true,
+		},
+		{
+			name:      "forbidden with quota left",
+			responses: []func(w http.ResponseWriter){status(http.StatusForbidden, "X-RateLimit-Remaining", "10")},
+			status:    http.StatusForbidden,
+			requests:  1,
+		},
+		{
+			name:        "long Retry-After",
+			responses:   []func(w http.ResponseWriter){status(http.StatusServiceUnavailable, "Retry-After", "3600")},
+			requests:    1,
+			rateLimited: true,
+		},
+	}
+
+	for _, tt := range tests {
+		srv, requests := respond(t, tt.responses...)
+		resp, err := New(WithRetries(2), WithTimeout(5*time.Second)).Get(srv.URL)
+
+		var rateLimit *RateLimitError
+		switch {
+		case tt.rateLimited:
+			if !errors.As(err, &rateLimit) {
+				t.Errorf("%s: got %v, want a rate limit error", tt.name, err)
+			} else if !tt.reset.IsZero() && !rateLimit.Reset.Equal(tt.reset) {
+				t.Errorf("%s: rate limit resets at %s, want %s", tt.name, rateLimit.Reset, tt.reset)
+			}
+		case err != nil:
+			t.Errorf("%s: request failed: %v", tt.name, err)
+		default:
+			if resp.StatusCode != tt.status {
+				t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.status)
+			}
+			resp.Body.Close()
+		}
+
+		if got := requests.Load(); got != tt.requests {
+			t.Errorf("%s: made %d requests, want %d", tt.name, got, tt.requests)
+		}
+	}
+}
+
+func TestRetryTransportReplaysBody(t *testing.T) {
+	var bodies []string
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("User-Agent") != UserAgent() {
+			t.Errorf("User-Agent = %q, want %q", r.Header.Get("User-Agent"), UserAgent())
+		}
+		if requests++; requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := New().Post(srv.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 || bodies[0] != "payload" || bodies[1] != "payload" {
+		t.Errorf("server received bodies %q, want the payload twice", bodies)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{value: "", ok: false},
+		{value: "120", want: 2 * time.Minute, ok: true},
+		{value: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), want: time.Hour, ok: true},
+		{value: "soon", ok: false},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", tt.value)
+
+		got, ok := retryAfter(resp)
+		if ok != tt.ok || got > tt.want || got < tt.want-2*time.Second {
+			t.Errorf("retryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/jasonuc/gignr/internal/templates"
+)
+
+// FileName is the name of the lockfile written next to the .gitignore
+const FileName = "gignr.lock"
+
+// Entry pins the template given as a create argument to a revision
+type Entry struct {
+	Template string `json:"template"`
+	templates.Pin
+}
+
+// Lock records the revision of every pinned template
+type Lock struct {
+	Templates []Entry `json:"templates"`
+
+	path    string
+	existed bool
+}
+
+// Load reads the lockfile at path. A missing file yields an empty lock
+// that will be created on Save.
+func Load(path string) (*Lock, error) {
+	l := &Lock{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %v", path, err)
+	}
+	l.existed = true
+	return l, nil
+}
+
+// Get returns the entry for a template argument
+func (l *Lock) Get(template string) (Entry, bool) {
+	for _, e := range l.Templates {
+		if e.Template == template {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Set adds or replaces the entry for a template argument
+func (l *Lock) Set(entry Entry) {
+	l.Remove(entry.Template)
+	l.Templates = append(l.Templates, entry)
+}
+
+// Remove drops the entry for a template argument
+func (l *Lock) Remove(template string) {
+	kept := l.Templates[:0]
+	for _, e := range l.Templates {
+		if e.Template != template {
+			kept = append(kept, e)
+		}
+	}
+	l.Templates = kept
+}
+
+// Retain drops the entries of templates that are no longer used
+func (l *Lock) Retain(used []string) {
+	keep := make(map[string]bool, len(used))
+	for _, t := range used {
+		keep[t] = true
+	}
+
+	kept := l.Templates[:0]
+	for _, e := range l.Templates {
+		if keep[e.Template] {
+			kept = append(kept, e)
+		}
+	}
+	l.Templates = kept
+}
+
+// Save writes the lockfile. Nothing is written when there is nothing to
+// pin and no lockfile existed before.
+func (l *Lock) Save() error {
+	if len(l.Templates) == 0 && !l.existed {
+		return nil
+	}
+
+	sort.Slice(l.Templates, func(i, j int) bool {
+		return l.Templates[i].Template < l.Templates[j].Template
+	})
+
+	content, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.path, append(content, '\n'), 0644)
+}
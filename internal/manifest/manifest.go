@@ -0,0 +1,69 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the default name of the manifest in a repository
+const FileName = ".gignr.yaml"
+
+// Manifest records how a repository's .gitignore is built
+type Manifest struct {
+	// Templates are the template arguments, as passed to gignr create
+	Templates []string `yaml:"templates"`
+	// Rules are project-specific patterns appended after the templates
+	Rules []string `yaml:"rules,omitempty"`
+	// Output is the path of the generated file, relative to the manifest
+	Output string `yaml:"output,omitempty"`
+	// Dedupe removes patterns already provided by an earlier template
+	Dedupe bool `yaml:"dedupe,omitempty"`
+
+	path string
+	dir  string
+}
+
+// Load reads and validates the manifest at path
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %v", path, err)
+	}
+	if len(m.Templates) == 0 && len(m.Rules) == 0 {
+		return nil, fmt.Errorf("manifest %s lists no templates or rules", path)
+	}
+
+	m.path = path
+	m.dir = filepath.Dir(path)
+	return &m, nil
+}
+
+// Path returns the path the manifest was loaded from
+func (m *Manifest) Path() string {
+	return m.path
+}
+
+// Dir returns the directory containing the manifest
+func (m *Manifest) Dir() string {
+	return m.dir
+}
+
+// OutputPath returns the path of the generated file
+func (m *Manifest) OutputPath() string {
+	output := m.Output
+	if output == "" {
+		output = ".gitignore"
+	}
+	if filepath.IsAbs(output) {
+		return output
+	}
+	return filepath.Join(m.dir, output)
+}
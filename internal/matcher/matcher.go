@@ -0,0 +1,172 @@
+package matcher
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jasonuc/gignr/internal/gitignore"
+)
+
+// Result describes how a path is treated by the .gitignore files
+type Result struct {
+	// Ignored reports whether the path is ignored
+	Ignored bool
+	// Rule is the last rule that matched, nil if no rule matched
+	Rule *Rule
+	// Path is the path the rule matched. It differs from the checked
+	// path when a parent directory is excluded, since files inside an
+	// excluded directory cannot be re-included.
+	Path string
+}
+
+// Matcher evaluates paths against the .gitignore files of a working tree
+type Matcher struct {
+	root  string
+	rules map[string][]*Rule
+}
+
+// New creates a matcher for the working tree at root
+func New(root string) *Matcher {
+	return &Matcher{
+		root:  root,
+		rules: make(map[string][]*Rule),
+	}
+}
+
+// FindRoot returns the closest directory at or above dir that contains
+// a .git entry, or dir itself when there is none
+func FindRoot(dir string) string {
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+// Rel converts a filesystem path to the slash-separated form relative to
+// the root that Match expects
+func (m *Matcher) Rel(p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(m.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the working tree %s", p, m.root)
+	}
+	if rel == "." {
+		return "", fmt.Errorf("%s is the root of the working tree", p)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Match decides whether path, relative to the root, is ignored
+func (m *Matcher) Match(rel string, isDir bool) (Result, error) {
+	parts := strings.Split(rel, "/")
+
+	// A file inside an excluded directory stays excluded
+	for i := 1; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		res, err := m.matchPath(dir, true)
+		if err != nil {
+			return Result{}, err
+		}
+		if res.Ignored {
+			return res, nil
+		}
+	}
+
+	return m.matchPath(rel, isDir)
+}
+
+func (m *Matcher) matchPath(rel string, isDir bool) (Result, error) {
+	res := Result{Path: rel}
+
+	// Rules in deeper .gitignore files take precedence, and within a file
+	// the last matching rule wins
+	dirs := []string{""}
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		dirs = append(dirs, strings.Join(parts[:i], "/"))
+	}
+
+	for _, dir := range dirs {
+		rules, err := m.load(dir)
+		if err != nil {
+			return Result{}, err
+		}
+		for _, r := range rules {
+			if r.match(rel, isDir) {
+				res.Rule = r
+				res.Ignored = !r.negated
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// load reads and caches the rules of the .gitignore file in dir
+func (m *Matcher) load(dir string) ([]*Rule, error) {
+	if rules, ok := m.rules[dir]; ok {
+		return rules, nil
+	}
+
+	file := path.Join(dir, ".gitignore")
+	data, err := os.ReadFile(filepath.Join(m.root, filepath.FromSlash(file)))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	rules := parseFile(file, dir, data)
+	m.rules[dir] = rules
+	return rules, nil
+}
+
+// parseFile compiles the rules of a .gitignore file, attributing each one
+// to the gignr-managed block it sits in
+func parseFile(file, dir string, data []byte) []*Rule {
+	var rules []*Rule
+	add := func(line string, number int, template string) {
+		if r := parseRule(line); r != nil {
+			r.File = file
+			r.Line = number
+			r.Template = template
+			r.base = dir
+			rules = append(rules, r)
+		}
+	}
+
+	parsed, err := gitignore.Parse(data)
+	if err != nil {
+		// Malformed markers, fall back to treating every line as a user rule
+		for i, line := range strings.Split(string(data), "\n") {
+			add(line, i+1, "")
+		}
+		return rules
+	}
+
+	number := 0
+	for _, s := range parsed.Sections {
+		if s.Managed() {
+			number++ // begin marker
+		}
+		for _, line := range s.Lines {
+			number++
+			add(line, number, s.Template)
+		}
+		if s.Managed() {
+			number++ // end marker
+		}
+	}
+	return rules
+}
@@ -0,0 +1,259 @@
+package matcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWildmatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"foo", "foo", true},
+		{"foo", "foobar", false},
+		{"*.log", "debug.log", true},
+		{"*.log", "logs/debug.log", false},
+		{"*", "", true},
+		{"a*b", "ab", true},
+		{"a*b", "a/b", false},
+		{"?.txt", "a.txt", true},
+		{"?.txt", "ab.txt", false},
+		{"a?b", "a/b", false},
+
+		// "**" as a whole segment
+		{"**/foo", "foo", true},
+		{"**/foo", "a/b/foo", true},
+		{"**/foo", "a/foo/b", false},
+		{"foo/**", "foo/a/b", true},
+		{"foo/**", "foo", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/c", false},
+		// "**" inside a segment behaves like "*"
+		{"a**b", "axxb", true},
+		{"a**b", "a/b", false},
+		{"**.log", "a/b.log", false},
+
+		// Bracket expressions
+		{"[abc].go", "b.go", true},
+		{"[abc].go", "d.go", false},
+		{"[a-c].go", "c.go", true},
+		{"[!a-c].go", "c.go", false},
+		{"[^a-c].go", "d.go", true},
+		{"[]].go", "].go", true},
+		{"[a-]", "-", true},
+		{"[\\]]", "]", true},
+		{"[/]", "/", false},
+		// A pattern with an unterminated "[" never matches
+		{"[abc", "[abc", false},
+		{"[abc", "a", false},
+		{"a[", "a[", false},
+		{"*[a-", "x[a-", false},
+
+		// Escapes
+		{"\\*", "*", true},
+		{"\\*", "a", false},
+		{"\\#foo", "#foo", true},
+		{"\\!important", "!important", true},
+		{"foo\\ ", "foo ", true},
+		{"foo\\?", "foo?", true},
+		{"foo\\?", "fooa", false},
+	}
+
+	for _, tt := range tests {
+		if got := wildmatch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("wildmatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		line     string
+		glob     string
+		negated  bool
+		dirOnly  bool
+		anchored bool
+	}{
+		{line: "*.log", glob: "*.log"},
+		{line: "!keep.log", glob: "keep.log", negated: true},
+		{line: "build/", glob: "build", dirOnly: true},
+		{line: "/build", glob: "build", anchored: true},
+		{line: "docs/*.md", glob: "docs/*.md", anchored: true},
+		{line: "trailing   ", glob: "trailing"},
+		{line: "escaped\\ ", glob: "escaped\\ "},
+		{line: "\\#hash", glob: "\\#hash"},
+		{line: "\\!bang", glob: "\\!bang"},
+		{line: "crlf\r", glob: "crlf"},
+	}
+
+	for _, tt := range tests {
+		r := parseRule(tt.line)
+		if r == nil {
+			t.Errorf("parseRule(%q) = nil", tt.line)
+			continue
+		}
+		if r.glob != tt.glob || r.negated != tt.negated || r.dirOnly != tt.dirOnly || r.anchored != tt.anchored {
+			t.Errorf("parseRule(%q) = {glob %q, negated %v, dirOnly %v, anchored %v}, want {glob %q, negated %v, dirOnly %v, anchored %v}",
+				tt.line, r.glob, r.negated, r.dirOnly, r.anchored, tt.glob, tt.negated, tt.dirOnly, tt.anchored)
+		}
+	}
+
+	for _, line := range []string{"", "# comment", "   ", "/", "!"} {
+		if r := parseRule(line); r != nil {
+			t.Errorf("parseRule(%q) = %+v, want nil", line, r)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, ".gitignore", `# comment
+*.log
+!keep.log
+/build
+out/
+docs/*.tmp
+**/cache/**
+vendor/
+!vendor/keep.go
+\#notes
+space\`+" "+`
+[abc
+# >>> gignr gh:Go
+*.test
+# <<< gignr gh:Go
+`)
+	writeFile(t, root, "sub/.gitignore", `!*.log
+local.txt
+/anchored.txt
+`)
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+		// pattern is the rule expected to decide, empty when none does
+		pattern string
+		// matched is the path the rule matched, defaulting to path
+		matched string
+	}{
+		{path: "main.go"},
+		{path: "debug.log", ignored: true, pattern: "*.log"},
+		{path: "a/b/debug.log", ignored: true, pattern: "*.log"},
+		{path: "keep.log", pattern: "!keep.log"},
+
+		// Anchored patterns only match relative to their .gitignore
+		{path: "build", isDir: true, ignored: true, pattern: "/build"},
+		{path: "src/build", isDir: true},
+		{path: "docs/a.tmp", ignored: true, pattern: "docs/*.tmp"},
+		{path: "docs/x/a.tmp"},
+
+		// Directory-only patterns
+		{path: "out", isDir: true, ignored: true, pattern: "out/"},
+		{path: "out"},
+		{path: "a/out", isDir: true, ignored: true, pattern: "out/"},
+
+		{path: "x/cache/y/z.bin", ignored: true, pattern: "**/cache/**", matched: "x/cache/y"},
+
+		// A file inside an excluded directory cannot be re-included
+		{path: "vendor/keep.go", ignored: true, pattern: "vendor/", matched: "vendor"},
+		{path: "build/app/main.go", ignored: true, pattern: "/build", matched: "build"},
+
+		// Escaped characters
+		{path: "#notes", ignored: true, pattern: "\\#notes"},
+		{path: "space ", ignored: true, pattern: "space\\ "},
+		{path: "space"},
+		{path: "[abc"},
+		{path: "a"},
+
+		// Rules inside managed blocks
+		{path: "pkg.test", ignored: true, pattern: "*.test"},
+
+		// Nested .gitignore files take precedence and are relative to
+		// their own directory
+		{path: "sub/debug.log", pattern: "!*.log"},
+		{path: "sub/local.txt", ignored: true, pattern: "local.txt"},
+		{path: "local.txt"},
+		{path: "sub/anchored.txt", ignored: true, pattern: "/anchored.txt"},
+		{path: "sub/deeper/anchored.txt"},
+	}
+
+	m := New(root)
+	for _, tt := range tests {
+		res, err := m.Match(tt.path, tt.isDir)
+		if err != nil {
+			t.Fatalf("Match(%q) failed: %v", tt.path, err)
+		}
+
+		if res.Ignored != tt.ignored {
+			t.Errorf("Match(%q).Ignored = %v, want %v", tt.path, res.Ignored, tt.ignored)
+		}
+
+		pattern := ""
+		if res.Rule != nil {
+			pattern = res.Rule.Pattern
+		}
+		if pattern != tt.pattern {
+			t.Errorf("Match(%q) decided by %q, want %q", tt.path, pattern, tt.pattern)
+		}
+
+		matched := tt.matched
+		if matched == "" {
+			matched = tt.path
+		}
+		if res.Path != matched {
+			t.Errorf("Match(%q).Path = %q, want %q", tt.path, res.Path, matched)
+		}
+	}
+}
+
+func TestMatchRuleLocation(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, ".gitignore", `bin/
+# >>> gignr gh:Go
+*.test
+# <<< gignr gh:Go
+`)
+
+	res, err := New(root).Match("x.test", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rule == nil {
+		t.Fatal("x.test is not matched")
+	}
+	if res.Rule.File != ".gitignore" || res.Rule.Line != 3 || res.Rule.Template != "gh:Go" {
+		t.Errorf("rule at %s:%d in %q, want .gitignore:3 in %q", res.Rule.File, res.Rule.Line, res.Rule.Template, "gh:Go")
+	}
+}
+
+func TestRel(t *testing.T) {
+	root := t.TempDir()
+	m := New(root)
+
+	rel, err := m.Rel(filepath.Join(root, "a", "b.txt"))
+	if err != nil || rel != "a/b.txt" {
+		t.Errorf("Rel = %q, %v, want a/b.txt", rel, err)
+	}
+	if _, err := m.Rel(root); err == nil {
+		t.Error("Rel of the root succeeded")
+	}
+	if _, err := m.Rel(filepath.Dir(root)); err == nil {
+		t.Error("Rel outside the root succeeded")
+	}
+}
+
+func writeFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
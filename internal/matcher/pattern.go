@@ -0,0 +1,212 @@
+package matcher
+
+import (
+	"strings"
+)
+
+// Rule is a single pattern from a .gitignore file
+type Rule struct {
+	// Pattern is the line as written in the file
+	Pattern string
+	// File is the path of the .gitignore file, relative to the root
+	File string
+	// Line is the 1-based line number in File
+	Line int
+	// Template is the gignr-managed block the rule belongs to, empty for
+	// rules written by hand
+	Template string
+
+	base     string
+	glob     string
+	negated  bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Negated reports whether the rule re-includes paths with "!"
+func (r *Rule) Negated() bool {
+	return r.negated
+}
+
+// parseRule compiles a .gitignore line, returning nil for blank lines
+// and comments
+func parseRule(line string) *Rule {
+	line = strings.TrimSuffix(line, "\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	r := &Rule{Pattern: line}
+
+	glob := trimTrailingSpaces(line)
+	if strings.HasPrefix(glob, "!") {
+		r.negated = true
+		glob = glob[1:]
+	}
+	if strings.HasSuffix(glob, "/") {
+		r.dirOnly = true
+		glob = strings.TrimRight(glob, "/")
+	}
+	if strings.Contains(glob, "/") {
+		r.anchored = true
+		glob = strings.TrimPrefix(glob, "/")
+	}
+	if glob == "" {
+		return nil
+	}
+
+	r.glob = glob
+	return r
+}
+
+// trimTrailingSpaces removes trailing spaces unless they are escaped
+// with a backslash
+func trimTrailingSpaces(s string) string {
+	end := len(s)
+	for end > 0 && s[end-1] == ' ' {
+		if end > 1 && s[end-2] == '\\' {
+			break
+		}
+		end--
+	}
+	return s[:end]
+}
+
+// match reports whether the rule matches path, given relative to the
+// root with forward slashes
+func (r *Rule) match(path string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	if r.base != "" {
+		if !strings.HasPrefix(path, r.base+"/") {
+			return false
+		}
+		path = strings.TrimPrefix(path, r.base+"/")
+	}
+
+	if !r.anchored {
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			path = path[i+1:]
+		}
+	}
+
+	return wildmatch(r.glob, path)
+}
+
+// wildmatch implements git's wildmatch with WM_PATHNAME: "*" and "?" do
+// not match "/", and "**" as a whole path segment matches any number of
+// directories.
+func wildmatch(pattern, name string) bool {
+	// "**" is only special as a whole segment of the pattern
+	segmentStart := true
+	for len(pattern) > 0 {
+		c := pattern[0]
+		wasSegmentStart := segmentStart
+		segmentStart = c == '/'
+
+		switch c {
+		case '*':
+			if strings.HasPrefix(pattern, "**") && wasSegmentStart && (len(pattern) == 2 || pattern[2] == '/') {
+				rest := pattern[2:]
+				if rest == "" {
+					return true
+				}
+				if rest[0] == '/' {
+					// "**/" matches zero or more leading directories
+					rest = rest[1:]
+					for {
+						if wildmatch(rest, name) {
+							return true
+						}
+						i := strings.Index(name, "/")
+						if i < 0 {
+							return false
+						}
+						name = name[i+1:]
+					}
+				}
+			}
+
+			rest := strings.TrimLeft(pattern, "*")
+			for i := 0; i <= len(name); i++ {
+				if wildmatch(rest, name[i:]) {
+					return true
+				}
+				if i < len(name) && name[i] == '/' {
+					return false
+				}
+			}
+			return false
+		case '?':
+			if name == "" || name[0] == '/' {
+				return false
+			}
+			pattern, name = pattern[1:], name[1:]
+		case '[':
+			if name == "" || name[0] == '/' {
+				return false
+			}
+			matched, width, ok := matchClass(pattern, name[0])
+			// As in git, a pattern with an unterminated class never
+			// matches
+			if !ok || !matched {
+				return false
+			}
+			pattern, name = pattern[width:], name[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if name == "" || name[0] != pattern[0] {
+				return false
+			}
+			pattern, name = pattern[1:], name[1:]
+		}
+	}
+	return name == ""
+}
+
+// matchClass matches c against the bracket expression at the start of
+// pattern, returning whether it matched and the width of the expression
+func matchClass(pattern string, c byte) (matched bool, width int, ok bool) {
+	i := 1
+	negate := false
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		negate = true
+		i++
+	}
+
+	first := true
+	for i < len(pattern) {
+		if pattern[i] == ']' && !first {
+			return matched != negate, i + 1, true
+		}
+		first = false
+
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+		i++
+
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi = pattern[i+1]
+			if hi == '\\' && i+2 < len(pattern) {
+				i++
+				hi = pattern[i+1]
+			}
+			i += 2
+		}
+
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+	return false, 0, false
+}
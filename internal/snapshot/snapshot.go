@@ -0,0 +1,53 @@
+// Package snapshot defines the format of the template snapshot embedded
+// in gignr. It has no dependencies on the rest of gignr, so the
+// generator can build before a snapshot exists.
+package snapshot
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Repos are the repositories of the built-in sources, which are embedded
+// in the binary so they work without a network
+var Repos = []string{"github/gitignore", "toptal/gitignore"}
+
+// Snapshot holds the `.gitignore` files of Repos at the time it was
+// generated. It is stored as gzipped JSON.
+type Snapshot struct {
+	Generated time.Time       `json:"generated"`
+	Repos     map[string]Repo `json:"repos"`
+}
+
+// Repo is a repository in a Snapshot, with file contents keyed by path
+type Repo struct {
+	Commit string            `json:"commit"`
+	Files  map[string]string `json:"files"`
+}
+
+// Encode writes s as gzipped JSON
+func Encode(w io.Writer, s Snapshot) error {
+	gz, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(gz).Encode(s); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Decode reads a snapshot written by Encode
+func Decode(r io.Reader) (Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer gz.Close()
+
+	var s Snapshot
+	err = json.NewDecoder(gz).Decode(&s)
+	return s, err
+}
@@ -0,0 +1,324 @@
+package templates
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jasonuc/gignr/internal/cache"
+)
+
+// maxArchiveSize limits the size of a downloaded archive and of the
+// templates extracted from it
+const maxArchiveSize = 64 << 20
+
+// IsArchive reports whether a repository argument is a .zip, .tar.gz or
+// .tgz file, given as a path or URL
+func IsArchive(location string) bool {
+	return archiveFormat(location) != ""
+}
+
+// archiveFormat returns "zip" or "tar.gz" depending on the extension of
+// an archive, or an empty string for other files
+func archiveFormat(location string) string {
+	p := location
+	if isRemote(location) {
+		if u, err := url.Parse(location); err == nil {
+			p = u.Path
+		}
+	}
+
+	p = strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(p, ".zip"):
+		return "zip"
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		return "tar.gz"
+	}
+	return ""
+}
+
+// ArchiveSource serves templates from a .zip or .tar.gz bundle. The
+// `.gitignore` files in it are unpacked under the cache directory.
+type ArchiveSource struct {
+	prefix    string
+	location  string
+	cacheFile string
+}
+
+func newArchiveSource(nickname, location string) (*ArchiveSource, error) {
+	if !isRemote(location) {
+		abs, err := ExpandPath(location)
+		if err != nil {
+			return nil, err
+		}
+		location = abs
+	}
+
+	return &ArchiveSource{
+		prefix:    nickname,
+		location:  location,
+		cacheFile: fmt.Sprintf("%s.json", nickname),
+	}, nil
+}
+
+func isRemote(location string) bool {
+	return strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://")
+}
+
+func (s *ArchiveSource) Prefix() string      { return s.prefix }
+func (s *ArchiveSource) DisplayName() string { return s.prefix }
+
+// dir is where the archive is unpacked
+func (s *ArchiveSource) dir() string {
+	return filepath.Join(cache.GetCacheDir(), "archives", s.prefix)
+}
+
+// List serves the templates of the unpacked archive. Templates are read
+// from the unpacked files, so an expired or modified archive is unpacked
+// again before listing rather than in the background, and the previous
+// copy is kept when the archive cannot be read.
+func (s *ArchiveSource) List() ([]Template, error) {
+	cached, hasCache := loadTemplatesCache(s.cacheFile)
+	hasCache = hasCache && s.unpacked()
+	if hasCache && !s.modifiedSince(cached) && (!cache.IsCacheExpired(cached.Updated) || cache.Offline()) {
+		return cached.Templates, nil
+	}
+
+	if err := s.Refresh(); err != nil {
+		if hasCache && s.unpacked() {
+			return cached.Templates, nil
+		}
+		return nil, err
+	}
+	return LoadCachedTemplates(s.cacheFile)
+}
+
+func (s *ArchiveSource) unpacked() bool {
+	_, err := os.Stat(s.dir())
+	return err == nil
+}
+
+// modifiedSince reports whether a local archive changed after it was
+// unpacked
+func (s *ArchiveSource) modifiedSince(cached TemplatesCache) bool {
+	if isRemote(s.location) {
+		return false
+	}
+	info, err := os.Stat(s.location)
+	return err == nil && info.ModTime().After(cached.Updated)
+}
+
+func (s *ArchiveSource) Fetch(name string) ([]byte, error) {
+	templates, err := s.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch templates from %s: %v", s.prefix, err)
+	}
+
+	tmpl, err := FindTemplate(name, templates)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(s.dir(), filepath.FromSlash(tmpl.Path)))
+}
+
+// Refresh downloads or reads the archive again and re-unpacks it
+func (s *ArchiveSource) Refresh() error {
+	templates, err := s.unpack()
+	if err != nil {
+		return err
+	}
+
+	replaceTemplatesCache(s.cacheFile, templates)
+	return nil
+}
+
+// HasGitignoreTemplates reports whether the archive holds any named
+// `.gitignore` files
+func (s *ArchiveSource) HasGitignoreTemplates() (bool, error) {
+	data, err := s.read()
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	err = walkArchive(s.location, data, func(name string, _ io.Reader) error {
+		found = true
+		return errStopWalk
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		return false, err
+	}
+	return found, nil
+}
+
+// unpack extracts the templates of the archive into a fresh directory,
+// replacing the previous one only once extraction succeeded
+func (s *ArchiveSource) unpack() ([]Template, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := s.dir()
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), s.prefix+".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	var total int64
+	err = walkArchive(s.location, data, func(name string, r io.Reader) error {
+		target := filepath.Join(tmp, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		n, err := io.Copy(f, io.LimitReader(r, maxArchiveSize-total+1))
+		total += n
+		if err != nil {
+			return err
+		}
+		if total > maxArchiveSize {
+			return fmt.Errorf("archive contents exceed %d MiB", maxArchiveSize>>20)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to unpack %s: %w", s.location, err)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return nil, err
+	}
+	return walkTemplates(dir, s.prefix)
+}
+
+// read returns the archive, downloading it when it is remote
+func (s *ArchiveSource) read() ([]byte, error) {
+	var r io.Reader
+	if isRemote(s.location) {
+		resp, err := httpClient.Get(s.location)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to download %s: %s", s.location, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(s.location)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArchiveSize {
+		return nil, fmt.Errorf("%s is larger than %d MiB", s.location, maxArchiveSize>>20)
+	}
+	return data, nil
+}
+
+var errStopWalk = errors.New("stop walking archive")
+
+// walkArchive calls fn for every named `.gitignore` file in a zip or
+// gzipped tar archive. Entries with paths escaping the archive root are
+// rejected.
+func walkArchive(location string, data []byte, fn func(name string, r io.Reader) error) error {
+	if archiveFormat(location) == "zip" {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return err
+		}
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() || !isTemplateFile(f.Name) {
+				continue
+			}
+			name, err := archivePath(f.Name)
+			if err != nil {
+				return err
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(name, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg || !isTemplateFile(hdr.Name) {
+			continue
+		}
+		name, err := archivePath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if err := fn(name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func isTemplateFile(name string) bool {
+	base := path.Base(name)
+	return strings.HasSuffix(base, ".gitignore") && base != ".gitignore"
+}
+
+// archivePath cleans the path of an archive entry and rejects paths that
+// would be extracted outside of the target directory
+func archivePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	clean := path.Clean("/" + name)[1:]
+	if clean == "" || strings.HasPrefix(name, "/") || strings.Contains("/"+name+"/", "/../") {
+		return "", fmt.Errorf("unsafe path %q in archive", name)
+	}
+	return clean, nil
+}
@@ -0,0 +1,42 @@
+package templates
+
+import "testing"
+
+func TestArchivePath(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{name: "Go.gitignore", want: "Go.gitignore", ok: true},
+		{name: "templates-main/Global/macOS.gitignore", want: "templates-main/Global/macOS.gitignore", ok: true},
+		{name: "./Go.gitignore", want: "Go.gitignore", ok: true},
+		{name: "a//b/./Go.gitignore", want: "a/b/Go.gitignore", ok: true},
+		{name: "templates/", want: "templates", ok: true},
+		{name: `templates\Go.gitignore`, want: "templates/Go.gitignore", ok: true},
+		{name: "..foo/Go.gitignore", want: "..foo/Go.gitignore", ok: true},
+
+		{name: ""},
+		{name: "."},
+		{name: "/"},
+		{name: ".."},
+		{name: "../Go.gitignore"},
+		{name: "templates/../../Go.gitignore"},
+		{name: "templates/../Go.gitignore"},
+		{name: "templates/.."},
+		{name: "/etc/passwd"},
+		{name: `\etc\passwd`},
+		{name: `..\Go.gitignore`},
+		{name: `templates\..\..\Go.gitignore`},
+	}
+
+	for _, tt := range tests {
+		got, err := archivePath(tt.name)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("archivePath(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("archivePath(%q) = %q, want an error", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package templates
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const bitbucketAPI = "https://api.bitbucket.org/2.0"
+
+// bitbucket is a repository on Bitbucket Cloud, accessed through the 2.0
+// REST API
+type bitbucket struct {
+	apiBase   string
+	workspace string
+	repo      string
+}
+
+// newBitbucket parses a repository URL, e.g.
+// https://bitbucket.org/workspace/repo
+func newBitbucket(u *url.URL, apiURL string) (*bitbucket, error) {
+	parts := repoPath(u)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Bitbucket URL must have the form https://bitbucket.org/{workspace}/{repo}")
+	}
+
+	if apiURL == "" {
+		apiURL = bitbucketAPI
+	}
+	return &bitbucket{
+		apiBase:   strings.TrimSuffix(apiURL, "/"),
+		workspace: parts[0],
+		repo:      parts[1],
+	}, nil
+}
+
+// bitbucketAuth returns the Authorization header for a token. An app
+// password is given as username:password, anything else is sent as an
+// access token.
+func bitbucketAuth(token string) string {
+	if strings.Contains(token, ":") {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(token))
+	}
+	return bearer(token)
+}
+
+func (b *bitbucket) repoID() string {
+	return "bitbucket.org/" + b.workspace + "/" + b.repo
+}
+
+func (b *bitbucket) repoURL() string {
+	return fmt.Sprintf("%s/repositories/%s/%s", b.apiBase, url.PathEscape(b.workspace), url.PathEscape(b.repo))
+}
+
+func (b *bitbucket) resolve(ref string) (string, error) {
+	if ref == "" {
+		var repo struct {
+			MainBranch struct {
+				Name string `json:"name"`
+			} `json:"mainbranch"`
+		}
+		if _, err := getJSON(b.repoURL(), &repo); err != nil {
+			return "", err
+		}
+		if repo.MainBranch.Name == "" {
+			return "", fmt.Errorf("repository %s/%s has no main branch", b.workspace, b.repo)
+		}
+		ref = repo.MainBranch.Name
+	}
+
+	var commit struct {
+		Hash string `json:"hash"`
+	}
+	if _, err := getJSON(b.repoURL()+"/commit/"+url.PathEscape(ref), &commit); err != nil {
+		return "", err
+	}
+	return commit.Hash, nil
+}
+
+// files walks the repository one directory at a time, following the
+// pagination of each listing
+func (b *bitbucket) files(commit string) ([]string, error) {
+	var files []string
+	dirs := []string{""}
+	for len(dirs) > 0 {
+		dir := dirs[0]
+		dirs = dirs[1:]
+
+		next := fmt.Sprintf("%s/src/%s/%s?pagelen=100", b.repoURL(), url.PathEscape(commit), escapePath(dir))
+		for next != "" {
+			var page struct {
+				Values []struct {
+					Path string `json:"path"`
+					Type string `json:"type"`
+				} `json:"values"`
+				Next string `json:"next"`
+			}
+			if _, err := getJSON(next, &page); err != nil {
+				return nil, err
+			}
+
+			for _, entry := range page.Values {
+				switch entry.Type {
+				case "commit_file":
+					files = append(files, entry.Path)
+				case "commit_directory":
+					dirs = append(dirs, entry.Path+"/")
+				}
+			}
+			next = page.Next
+		}
+	}
+	return files, nil
+}
+
+func (b *bitbucket) rawURL(commit, filePath string) string {
+	return fmt.Sprintf("%s/src/%s/%s", b.repoURL(), url.PathEscape(commit), escapePath(filePath))
+}
+
+func (b *bitbucket) authenticate(token string) error {
+	return setAPIAuth(b.apiBase, bitbucketAuth(token))
+}
@@ -2,81 +2,197 @@ package templates
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/jasonuc/gignr/internal/cache"
 )
 
 type TemplatesCache struct {
-	Updated   time.Time  `json:"updated"`
+	Updated time.Time `json:"updated"`
+	cache.Validators
 	Templates []Template `json:"templates"`
 }
 
-// LoadCachedTemplates retrieves the list of available templates from cache
+// LoadCachedTemplates retrieves the list of available templates from
+// cache, regardless of its age
 func LoadCachedTemplates(source string) ([]Template, error) {
+	cacheData, ok := loadTemplatesCache(source)
+	if !ok {
+		return nil, fmt.Errorf("cache missing")
+	}
+	return cacheData.Templates, nil
+}
 
-	var cacheData TemplatesCache
+// listCached serves the listing in cacheFile, calling fetch to fill the
+// cache when it is missing. An expired listing is served right away and
+// refreshed in the background.
+func listCached(cacheFile string, fetch func() ([]Template, error)) ([]Template, error) {
+	if cached, ok := loadTemplatesCache(cacheFile); ok {
+		if cache.IsCacheExpired(cached.Updated) {
+			revalidate(cacheFile, func() error {
+				templates, err := fetch()
+				if err != nil {
+					return err
+				}
+				replaceTemplatesCache(cacheFile, templates)
+				return nil
+			})
+		}
+		return cached.Templates, nil
+	}
 
-	if err := cache.LoadCache(source, &cacheData); err != nil || cache.IsCacheExpired(cacheData.Updated) {
-		return nil, fmt.Errorf("cache expired or missing")
+	templates, err := fetch()
+	if err != nil {
+		return nil, err
 	}
 
-	return cacheData.Templates, nil
+	SaveTemplatesToCache(cacheFile, templates)
+	return templates, nil
 }
 
-// SaveTemplatesToCache stores fetched templates in the cache
+var (
+	// revalidating holds the keys of the refreshes started by this
+	// process, so each cache entry is refreshed at most once
+	revalidating sync.Map
+	revalidateWg sync.WaitGroup
+)
+
+// revalidate runs refresh in the background. Failures are ignored, the
+// stale entry is kept and refreshed again on a later run.
+func revalidate(key string, refresh func() error) {
+	if cache.Offline() {
+		return
+	}
+	if _, started := revalidating.LoadOrStore(key, true); started {
+		return
+	}
+
+	revalidateWg.Add(1)
+	go func() {
+		defer revalidateWg.Done()
+		refresh()
+	}()
+}
+
+// WaitForRevalidation waits up to timeout for background refreshes, so
+// their results are saved before gignr exits
+func WaitForRevalidation(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		revalidateWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// loadTemplatesCache retrieves a cached listing even when it is expired,
+// so it can be revalidated
+func loadTemplatesCache(source string) (TemplatesCache, bool) {
+	var cacheData TemplatesCache
+	if err := cache.LoadCache(source, &cacheData); err != nil || len(cacheData.Templates) == 0 {
+		return TemplatesCache{}, false
+	}
+	return cacheData, true
+}
+
+// SaveTemplatesToCache stores fetched templates in the cache, merging
+// them into a listing that has not expired yet
 func SaveTemplatesToCache(cacheFile string, newTemplates []Template) {
-	var existingCache TemplatesCache
-	cache.LoadCache(cacheFile, &existingCache)
+	saveTemplatesCache(cacheFile, newTemplates, cache.Validators{}, false)
+}
 
-	if !cache.IsCacheExpired(existingCache.Updated) {
-		existing := make(map[string]Template)
-		for _, t := range existingCache.Templates {
-			existing[t.Path] = t
-		}
+// replaceTemplatesCache stores a complete listing re-fetched from a
+// source, dropping templates that are no longer listed
+func replaceTemplatesCache(cacheFile string, newTemplates []Template) {
+	saveTemplatesCache(cacheFile, newTemplates, cache.Validators{}, true)
+}
 
-		for _, t := range newTemplates {
-			existing[t.Path] = t
+func saveTemplatesCache(cacheFile string, newTemplates []Template, validators cache.Validators, replace bool) {
+	var cacheData TemplatesCache
+	cache.UpdateCache(cacheFile, &cacheData, func() bool {
+		if !replace && !cache.IsCacheExpired(cacheData.Updated) {
+			newTemplates = mergeTemplates(cacheData.Templates, newTemplates)
 		}
 
-		var merged []Template
-		for _, t := range existing {
-			merged = append(merged, t)
+		cacheData = TemplatesCache{
+			Updated:    time.Now(),
+			Validators: validators,
+			Templates:  newTemplates,
 		}
-		newTemplates = merged
+		return true
+	})
+}
+
+// mergeTemplates adds templates to a listing, replacing those with the
+// same path in place so the order of the listing is kept
+func mergeTemplates(existing, templates []Template) []Template {
+	index := make(map[string]int, len(existing))
+	merged := append([]Template(nil), existing...)
+	for i, t := range merged {
+		index[t.Path] = i
 	}
 
-	cacheData := TemplatesCache{
-		Updated:   time.Now(),
-		Templates: newTemplates,
+	for _, t := range templates {
+		if i, ok := index[t.Path]; ok {
+			merged[i] = t
+			continue
+		}
+		index[t.Path] = len(merged)
+		merged = append(merged, t)
 	}
+	return merged
+}
 
+// touchTemplatesCache marks a revalidated listing as fresh again
+func touchTemplatesCache(cacheFile string, cacheData TemplatesCache) {
+	cacheData.Updated = time.Now()
 	cache.SaveCache(cacheFile, cacheData)
 }
 
-// LoadCachedTemplateContent retrieves the cached content of a specific template
-func LoadCachedTemplateContent(url string) ([]byte, error) {
+// loadTemplateContentCache retrieves a cached content entry even when it
+// is expired, so it can be revalidated
+func loadTemplateContentCache(url string) (cache.TemplateContentCache, bool) {
 	var cacheData = make(map[string]cache.TemplateContentCache)
 
-	if err := cache.LoadCache("template-content.json", &cacheData); err == nil {
-		if entry, exists := cacheData[url]; exists && !cache.IsCacheExpired(entry.Updated) {
-			return []byte(entry.Content), nil
-		}
+	if err := cache.LoadCache("template-content.json", &cacheData); err != nil {
+		return cache.TemplateContentCache{}, false
 	}
 
-	return nil, fmt.Errorf("content not found in cache")
+	entry, exists := cacheData[url]
+	return entry, exists
 }
 
 // SaveTemplateContentToCache stores fetched `.gitignore` content in the cache
-func SaveTemplateContentToCache(url, content string) {
+func SaveTemplateContentToCache(url, content string, validators cache.Validators) {
 	var cacheData = make(map[string]cache.TemplateContentCache)
+	cache.UpdateCache("template-content.json", &cacheData, func() bool {
+		cacheData[url] = cache.TemplateContentCache{
+			Updated:    time.Now(),
+			Content:    content,
+			Validators: validators,
+		}
+		return true
+	})
+}
 
-	cache.LoadCache("template-content.json", &cacheData)
-
-	cacheData[url] = cache.TemplateContentCache{
-		Updated: time.Now(),
-		Content: content,
-	}
-
-	cache.SaveCache("template-content.json", cacheData)
+// PruneTemplateContent removes expired entries from the content cache
+// and returns how many were removed
+func PruneTemplateContent() (int, error) {
+	var pruned int
+	var cacheData = make(map[string]cache.TemplateContentCache)
+	err := cache.UpdateCache("template-content.json", &cacheData, func() bool {
+		for url, entry := range cacheData {
+			if cache.IsCacheExpired(entry.Updated) {
+				delete(cacheData, url)
+				pruned++
+			}
+		}
+		return pruned > 0
+	})
+	return pruned, err
 }
@@ -1,27 +1,79 @@
 package templates
 
 import (
+	"fmt"
 	"io"
 	"net/http"
+
+	"github.com/jasonuc/gignr/internal/cache"
 )
 
-// GetTemplateContent retrieves a `.gitignore` template (cached or fresh)
+// GetTemplateContent retrieves a `.gitignore` template (cached or fresh).
+// Expired entries are served right away and revalidated in the
+// background with a conditional request. Offline, only cached entries
+// are served.
 func GetTemplateContent(url string) ([]byte, error) {
-	if cacheContent, err := LoadCachedTemplateContent(url); err == nil {
-		return cacheContent, nil
+	cached, hasCache := loadTemplateContentCache(url)
+	if hasCache {
+		if cache.IsCacheExpired(cached.Updated) {
+			revalidate("content:"+url, func() error {
+				_, err := downloadTemplateContent(url, cached, true)
+				return err
+			})
+		}
+		return []byte(cached.Content), nil
 	}
 
-	resp, err := http.Get(url)
+	if cache.Offline() {
+		return nil, fmt.Errorf("%s is not cached: %w", url, ErrOffline)
+	}
+	return downloadTemplateContent(url, cached, false)
+}
+
+// WarmTemplateContent downloads content that is missing from the cache
+// or expired, and reports whether a request was made
+func WarmTemplateContent(url string) (bool, error) {
+	cached, hasCache := loadTemplateContentCache(url)
+	if hasCache && !cache.IsCacheExpired(cached.Updated) {
+		return false, nil
+	}
+
+	_, err := downloadTemplateContent(url, cached, hasCache)
+	return true, err
+}
+
+// downloadTemplateContent fetches content and stores it in the cache.
+// With a cached entry the request is conditional, and the entry is kept
+// when the server answers 304 Not Modified.
+func downloadTemplateContent(url string, cached cache.TemplateContentCache, hasCache bool) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if hasCache {
+		cached.Validators.Apply(req)
+	}
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && hasCache {
+		SaveTemplateContentToCache(url, cached.Content, cached.Validators)
+		return []byte(cached.Content), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	SaveTemplateContentToCache(url, string(body))
+	SaveTemplateContentToCache(url, string(body), cache.ValidatorsFrom(resp))
 	return body, nil
 }
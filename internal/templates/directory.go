@@ -0,0 +1,112 @@
+package templates
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DirectorySource serves templates from a directory on disk, such as a
+// checked-out repository or a network mount. Files are read directly, so
+// changes show up without refreshing a cache.
+type DirectorySource struct {
+	prefix string
+	root   string
+}
+
+// IsLocalPath reports whether a repository argument is a path on disk
+// rather than a URL
+func IsLocalPath(p string) bool {
+	return !strings.Contains(p, "://") && !scpLikeURL.MatchString(p)
+}
+
+// ExpandPath expands a leading ~ and makes a path absolute
+func ExpandPath(p string) (string, error) {
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		p = filepath.Join(home, p[1:])
+	}
+	return filepath.Abs(p)
+}
+
+func newDirectorySource(nickname, dir string) (*DirectorySource, error) {
+	root, err := ExpandPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &DirectorySource{prefix: nickname, root: root}, nil
+}
+
+func (s *DirectorySource) Prefix() string      { return s.prefix }
+func (s *DirectorySource) DisplayName() string { return s.prefix }
+
+func (s *DirectorySource) List() ([]Template, error) {
+	info, err := os.Stat(s.root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", s.root)
+	}
+	return walkTemplates(s.root, s.prefix)
+}
+
+func (s *DirectorySource) Fetch(name string) ([]byte, error) {
+	templates, err := s.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read templates from %s: %v", s.prefix, err)
+	}
+
+	tmpl, err := FindTemplate(name, templates)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(s.root, filepath.FromSlash(tmpl.Path)))
+}
+
+// HasGitignoreTemplates reports whether the directory holds any named
+// `.gitignore` files
+func (s *DirectorySource) HasGitignoreTemplates() (bool, error) {
+	templates, err := s.List()
+	if err != nil {
+		return false, err
+	}
+	return len(templates) > 0, nil
+}
+
+// walkTemplates lists the named `.gitignore` files below root, skipping
+// .git directories. Paths are relative to root and slash separated.
+func walkTemplates(root, source string) ([]Template, error) {
+	var templates []Template
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".gitignore") || d.Name() == ".gitignore" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		templates = append(templates, Template{
+			Name:   d.Name(),
+			Path:   filepath.ToSlash(rel),
+			Source: source,
+		})
+		return nil
+	})
+	return templates, err
+}
@@ -0,0 +1,246 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// forge is the API of a git hosting service templates can be listed and
+// downloaded from
+type forge interface {
+	// repoID identifies the repository, e.g. gitlab.com/group/project
+	repoID() string
+	// resolve returns the commit ref points at, or the head of the
+	// default branch when ref is empty
+	resolve(ref string) (string, error)
+	// files lists the paths of all files at commit
+	files(commit string) ([]string, error)
+	// rawURL returns the download URL of a file at commit
+	rawURL(commit, filePath string) string
+	// authenticate sends token with requests to the API
+	authenticate(token string) error
+}
+
+// ForgeSource serves templates stored as `.gitignore` files in a
+// repository on GitLab, Gitea or Bitbucket
+type ForgeSource struct {
+	prefix    string
+	cacheFile string
+	forge     forge
+}
+
+func (s *ForgeSource) Prefix() string      { return s.prefix }
+func (s *ForgeSource) DisplayName() string { return s.prefix }
+
+func (s *ForgeSource) List() ([]Template, error) {
+	return listCached(s.cacheFile, func() ([]Template, error) {
+		templates, err := s.listAt("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch templates: %w", err)
+		}
+		return templates, nil
+	})
+}
+
+func (s *ForgeSource) Fetch(name string) ([]byte, error) {
+	templates, err := s.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch templates from %s: %v", s.prefix, err)
+	}
+
+	tmpl, err := FindTemplate(name, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := GetTemplateContent(tmpl.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch content: %v", err)
+	}
+	return content, nil
+}
+
+// Refresh re-fetches the repository listing and replaces the cached one
+func (s *ForgeSource) Refresh() error {
+	templates, err := s.listAt("")
+	if err != nil {
+		return fmt.Errorf("failed to fetch templates: %w", err)
+	}
+
+	replaceTemplatesCache(s.cacheFile, templates)
+	return nil
+}
+
+// HasGitignoreTemplates reports whether the repository holds any named
+// `.gitignore` files
+func (s *ForgeSource) HasGitignoreTemplates() (bool, error) {
+	templates, err := s.listAt("")
+	if err != nil {
+		return false, err
+	}
+	return len(templates) > 0, nil
+}
+
+func (s *ForgeSource) Resolve(name, ref string) ([]byte, Pin, error) {
+	commit, err := s.forge.resolve(ref)
+	if err != nil {
+		return nil, Pin{}, fmt.Errorf("unable to resolve %s of %s: %w", refOrHead(ref), s.forge.repoID(), err)
+	}
+
+	var templates []Template
+	if ref == "" {
+		templates, err = s.List()
+	} else {
+		templates, err = s.listCommit(commit)
+	}
+	if err != nil {
+		return nil, Pin{}, fmt.Errorf("unable to fetch templates from %s: %v", s.prefix, err)
+	}
+
+	tmpl, err := FindTemplate(name, templates)
+	if err != nil {
+		return nil, Pin{}, err
+	}
+
+	content, err := GetTemplateContent(s.forge.rawURL(commit, tmpl.Path))
+	if err != nil {
+		return nil, Pin{}, fmt.Errorf("unable to fetch content: %v", err)
+	}
+
+	return content, Pin{
+		Repo:   s.forge.repoID(),
+		Path:   tmpl.Path,
+		Commit: commit,
+		SHA256: ContentHash(content),
+	}, nil
+}
+
+func (s *ForgeSource) FetchPinned(pin Pin) ([]byte, error) {
+	if !strings.EqualFold(pin.Repo, s.forge.repoID()) {
+		return nil, ErrStalePin
+	}
+
+	content, err := GetTemplateContent(s.forge.rawURL(pin.Commit, pin.Path))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch content: %v", err)
+	}
+
+	if err := verifyPin(content, pin); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// listCommit lists the templates at a commit. The listing of a commit
+// never changes, so it is cached under its own file.
+func (s *ForgeSource) listCommit(commit string) ([]Template, error) {
+	cacheFile := fmt.Sprintf("%s@%s.json", strings.TrimSuffix(s.cacheFile, ".json"), commit)
+	if templates, err := LoadCachedTemplates(cacheFile); err == nil {
+		return templates, nil
+	}
+
+	templates, err := s.listAt(commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch templates: %w", err)
+	}
+
+	SaveTemplatesToCache(cacheFile, templates)
+	return templates, nil
+}
+
+// listAt lists the templates at a commit, or at the head of the default
+// branch when commit is empty
+func (s *ForgeSource) listAt(commit string) ([]Template, error) {
+	if commit == "" {
+		var err error
+		if commit, err = s.forge.resolve(""); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := s.forge.files(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []Template
+	for _, file := range files {
+		name := path.Base(file)
+		if !strings.HasSuffix(name, ".gitignore") || name == ".gitignore" {
+			continue
+		}
+		templates = append(templates, Template{
+			Name:        name,
+			Path:        file,
+			DownloadURL: s.forge.rawURL(commit, file),
+			Source:      s.prefix,
+		})
+	}
+	return templates, nil
+}
+
+func refOrHead(ref string) string {
+	if ref == "" {
+		return "HEAD"
+	}
+	return ref
+}
+
+// getJSON decodes the JSON response of a GET request into v and returns
+// the response headers
+func getJSON(u string, v any) (http.Header, error) {
+	resp, err := httpClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s not found or not accessible with the current token", u)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("request to %s failed: %s", u, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %v", u, err)
+	}
+	return resp.Header, nil
+}
+
+// setAPIAuth sets the Authorization header sent to the host of apiBase
+func setAPIAuth(apiBase, header string) error {
+	u, err := url.Parse(apiBase)
+	if err != nil {
+		return err
+	}
+	return setHostAuth(u.Hostname(), header)
+}
+
+// escapePath escapes each segment of a slash separated path
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// repoPath splits the path of a repository URL into its segments,
+// dropping a trailing .git
+func repoPath(u *url.URL) []string {
+	p := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
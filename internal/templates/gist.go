@@ -0,0 +1,283 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GistPrefix addresses templates in any gist as gist:<id>/<file>
+const GistPrefix = "gist"
+
+var gistID = regexp.MustCompile(`^[0-9A-Za-z]+$`)
+
+// GistSource serves the `.gitignore` files of GitHub gists. A source
+// added by nickname serves a single gist, while the built-in gist prefix
+// takes the gist ID as part of the name, as in gist:<id>/<file>.
+type GistSource struct {
+	prefix string
+	// id is empty for the built-in gist prefix
+	id string
+}
+
+// IsGistURL reports whether a repository argument refers to a gist,
+// either as gist:<id> or as a gist.github.com URL
+func IsGistURL(u string) bool {
+	if strings.HasPrefix(u, GistPrefix+":") {
+		return true
+	}
+	parsed, err := url.Parse(u)
+	return err == nil && parsed.Hostname() == "gist.github.com"
+}
+
+// parseGistID extracts the ID from gist:<id> or
+// https://gist.github.com/<user>/<id>
+func parseGistID(u string) (string, error) {
+	id, ok := strings.CutPrefix(u, GistPrefix+":")
+	if !ok {
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Hostname() != "gist.github.com" {
+			return "", fmt.Errorf("gist must be given as gist:<id> or https://gist.github.com/<user>/<id>")
+		}
+		parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+		id = strings.TrimSuffix(parts[len(parts)-1], ".git")
+	}
+
+	if !gistID.MatchString(id) {
+		return "", fmt.Errorf("invalid gist ID %q", id)
+	}
+	return id, nil
+}
+
+func newGistSource(nickname, u string) (*GistSource, error) {
+	id, err := parseGistID(u)
+	if err != nil {
+		return nil, err
+	}
+	return &GistSource{prefix: nickname, id: id}, nil
+}
+
+func (s *GistSource) Prefix() string { return s.prefix }
+
+func (s *GistSource) DisplayName() string {
+	if s.id == "" {
+		return "Gist"
+	}
+	return s.prefix
+}
+
+func (s *GistSource) List() ([]Template, error) {
+	if s.id == "" {
+		return nil, fmt.Errorf("templates from any gist are fetched as %s:<id>/<file>", GistPrefix)
+	}
+	return s.list(s.id, "")
+}
+
+func (s *GistSource) Fetch(name string) ([]byte, error) {
+	id, file, err := s.split(name)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := s.list(id, "")
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch gist %s: %v", id, err)
+	}
+
+	tmpl, err := FindTemplate(file, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := GetTemplateContent(tmpl.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch content: %v", err)
+	}
+	return content, nil
+}
+
+// Refresh re-fetches the gist and replaces the cached listing
+func (s *GistSource) Refresh() error {
+	if s.id == "" {
+		return nil
+	}
+
+	templates, _, err := fetchGist(s.id, "")
+	if err != nil {
+		return err
+	}
+	replaceTemplatesCache(gistCacheFile(s.id, ""), templates)
+	return nil
+}
+
+// HasGitignoreTemplates reports whether the gist holds any named
+// `.gitignore` files
+func (s *GistSource) HasGitignoreTemplates() (bool, error) {
+	templates, _, err := fetchGist(s.id, "")
+	if err != nil {
+		return false, err
+	}
+	return len(templates) > 0, nil
+}
+
+// Resolve fetches a template at a revision of the gist. Gists have no
+// branches or tags, so ref must be a revision SHA when given.
+func (s *GistSource) Resolve(name, ref string) ([]byte, Pin, error) {
+	id, file, err := s.split(name)
+	if err != nil {
+		return nil, Pin{}, err
+	}
+
+	templates, version, err := fetchGist(id, ref)
+	if err != nil {
+		return nil, Pin{}, fmt.Errorf("unable to resolve %s of gist %s: %w", refOrHead(ref), id, err)
+	}
+	SaveTemplatesToCache(gistCacheFile(id, version), templates)
+
+	tmpl, err := FindTemplate(file, templates)
+	if err != nil {
+		return nil, Pin{}, err
+	}
+
+	content, err := GetTemplateContent(tmpl.DownloadURL)
+	if err != nil {
+		return nil, Pin{}, fmt.Errorf("unable to fetch content: %v", err)
+	}
+
+	return content, Pin{
+		Repo:   GistPrefix + ":" + id,
+		Path:   tmpl.Path,
+		Commit: version,
+		SHA256: ContentHash(content),
+	}, nil
+}
+
+func (s *GistSource) FetchPinned(pin Pin) ([]byte, error) {
+	id, ok := strings.CutPrefix(pin.Repo, GistPrefix+":")
+	if !ok || (s.id != "" && id != s.id) {
+		return nil, ErrStalePin
+	}
+
+	templates, err := s.list(id, pin.Commit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch revision %s of gist %s: %v", pin.Commit, id, err)
+	}
+
+	var tmpl *Template
+	for i := range templates {
+		if templates[i].Path == pin.Path {
+			tmpl = &templates[i]
+			break
+		}
+	}
+	if tmpl == nil {
+		return nil, fmt.Errorf("%s not found in revision %s of gist %s", pin.Path, pin.Commit, id)
+	}
+
+	content, err := GetTemplateContent(tmpl.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch content: %v", err)
+	}
+
+	if err := verifyPin(content, pin); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// split returns the gist ID and template name of a name given to the
+// source, which includes the ID for the built-in gist prefix
+func (s *GistSource) split(name string) (id, file string, err error) {
+	if s.id != "" {
+		return s.id, name, nil
+	}
+
+	id, file, ok := strings.Cut(name, "/")
+	if !ok || !gistID.MatchString(id) || file == "" {
+		return "", "", fmt.Errorf("gist templates are written as %s:<id>/<file>", GistPrefix)
+	}
+	return id, file, nil
+}
+
+// list returns the templates of a gist at a revision, or at its latest
+// revision when version is empty, using the cache when possible. A
+// revision never changes, so its listing is never refreshed.
+func (s *GistSource) list(id, version string) ([]Template, error) {
+	cacheFile := gistCacheFile(id, version)
+	if version != "" {
+		if templates, err := LoadCachedTemplates(cacheFile); err == nil {
+			return templates, nil
+		}
+	}
+
+	return listCached(cacheFile, func() ([]Template, error) {
+		templates, _, err := fetchGist(id, version)
+		return templates, err
+	})
+}
+
+func gistCacheFile(id, version string) string {
+	if version == "" {
+		return fmt.Sprintf("gist-%s.json", id)
+	}
+	return fmt.Sprintf("gist-%s@%s.json", id, version)
+}
+
+// fetchGist lists the `.gitignore` files of a gist at a revision, or at
+// its latest revision when version is empty, and returns the revision
+func fetchGist(id, version string) ([]Template, string, error) {
+	if version != "" && !gistID.MatchString(version) {
+		return nil, "", fmt.Errorf("invalid gist revision %q", version)
+	}
+
+	u := "gists/" + id
+	if version != "" {
+		u += "/" + version
+	}
+
+	client := publicGitHub.client
+	req, err := client.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// go-github does not expose the revision history of a gist
+	var gist struct {
+		Files map[string]struct {
+			RawURL string `json:"raw_url"`
+		} `json:"files"`
+		History []struct {
+			Version string `json:"version"`
+		} `json:"history"`
+	}
+	if _, err := client.Do(context.Background(), req, &gist); err != nil {
+		return nil, "", err
+	}
+
+	if version == "" {
+		if len(gist.History) == 0 {
+			return nil, "", fmt.Errorf("gist %s has no revisions", id)
+		}
+		version = gist.History[0].Version
+	}
+
+	var templates []Template
+	for name, file := range gist.Files {
+		if !strings.HasSuffix(name, ".gitignore") || name == ".gitignore" {
+			continue
+		}
+		templates = append(templates, Template{
+			Name:        name,
+			Path:        name,
+			DownloadURL: file.RawURL,
+			Source:      GistPrefix + ":" + id,
+		})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	return templates, version, nil
+}
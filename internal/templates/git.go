@@ -0,0 +1,318 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jasonuc/gignr/internal/cache"
+)
+
+// commitSHA matches abbreviated and full SHA-1 and SHA-256 object names
+var commitSHA = regexp.MustCompile(`^[0-9a-fA-F]{4,64}$`)
+
+// scpLikeURL matches the scp-like syntax of ssh remotes, e.g.
+// git@host:org/templates.git
+var scpLikeURL = regexp.MustCompile(`^[\w.-]+@[\w.-]+:[^/]`)
+
+// IsGitURL reports whether a URL can only be accessed with git, such as
+// ssh, git:// and file:// remotes
+func IsGitURL(u string) bool {
+	if scpLikeURL.MatchString(u) {
+		return true
+	}
+	for _, scheme := range []string{"ssh://", "git://", "git+ssh://", "file://"} {
+		if strings.HasPrefix(u, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// GitSource serves templates from a shallow clone of a git remote, kept
+// under the cache directory and updated with fetch
+type GitSource struct {
+	prefix    string
+	remote    string
+	cacheFile string
+}
+
+func newGitSource(nickname, remote string) *GitSource {
+	return &GitSource{
+		prefix:    nickname,
+		remote:    remote,
+		cacheFile: fmt.Sprintf("%s.json", nickname),
+	}
+}
+
+func (s *GitSource) Prefix() string      { return s.prefix }
+func (s *GitSource) DisplayName() string { return s.prefix }
+
+// dir is the location of the clone
+func (s *GitSource) dir() string {
+	return filepath.Join(cache.GetCacheDir(), "repos", s.prefix)
+}
+
+// List serves the templates of the clone. Templates are read from its
+// working tree, so an expired clone is updated before listing rather
+// than in the background, and kept as is when the remote cannot be
+// reached.
+func (s *GitSource) List() ([]Template, error) {
+	cached, hasCache := loadTemplatesCache(s.cacheFile)
+	hasCache = hasCache && s.cloned()
+	if hasCache && (!cache.IsCacheExpired(cached.Updated) || cache.Offline()) {
+		return cached.Templates, nil
+	}
+
+	if err := s.Refresh(); err != nil {
+		if hasCache && s.cloned() {
+			return cached.Templates, nil
+		}
+		return nil, err
+	}
+	return LoadCachedTemplates(s.cacheFile)
+}
+
+func (s *GitSource) Fetch(name string) ([]byte, error) {
+	templates, err := s.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch templates from %s: %v", s.prefix, err)
+	}
+
+	tmpl, err := FindTemplate(name, templates)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(s.dir(), filepath.FromSlash(tmpl.Path)))
+}
+
+// Refresh fetches the head of the remote's default branch and re-lists
+// the working tree
+func (s *GitSource) Refresh() error {
+	if err := s.sync(); err != nil {
+		return err
+	}
+
+	templates, err := s.walk()
+	if err != nil {
+		return err
+	}
+	replaceTemplatesCache(s.cacheFile, templates)
+	return nil
+}
+
+// HasGitignoreTemplates reports whether the remote holds any named
+// `.gitignore` files
+func (s *GitSource) HasGitignoreTemplates() (bool, error) {
+	if err := s.sync(); err != nil {
+		return false, err
+	}
+
+	templates, err := s.walk()
+	if err != nil {
+		return false, err
+	}
+	return len(templates) > 0, nil
+}
+
+func (s *GitSource) Resolve(name, ref string) ([]byte, Pin, error) {
+	var commit string
+	var templates []Template
+	var err error
+	if ref == "" {
+		if templates, err = s.List(); err != nil {
+			return nil, Pin{}, fmt.Errorf("unable to fetch templates from %s: %v", s.prefix, err)
+		}
+		commit, err = s.git("rev-parse", "HEAD")
+	} else {
+		commit, err = s.fetchRef(ref)
+		if err == nil {
+			templates, err = s.listCommit(commit)
+		}
+	}
+	if err != nil {
+		return nil, Pin{}, fmt.Errorf("unable to resolve %s of %s: %w", refOrHead(ref), s.remote, err)
+	}
+
+	tmpl, err := FindTemplate(name, templates)
+	if err != nil {
+		return nil, Pin{}, err
+	}
+
+	content, err := s.show(commit, tmpl.Path)
+	if err != nil {
+		return nil, Pin{}, err
+	}
+
+	return content, Pin{
+		Repo:   s.remote,
+		Path:   tmpl.Path,
+		Commit: commit,
+		SHA256: ContentHash(content),
+	}, nil
+}
+
+func (s *GitSource) FetchPinned(pin Pin) ([]byte, error) {
+	if pin.Repo != s.remote {
+		return nil, ErrStalePin
+	}
+
+	if !s.cloned() {
+		if err := s.sync(); err != nil {
+			return nil, err
+		}
+	}
+	if len(pin.Commit) < 40 || !commitSHA.MatchString(pin.Commit) {
+		return nil, fmt.Errorf("invalid pinned commit %q", pin.Commit)
+	}
+	if _, err := s.git("cat-file", "-e", "--end-of-options", pin.Commit+"^{commit}"); err != nil {
+		if _, err := s.fetchRef(pin.Commit); err != nil {
+			return nil, fmt.Errorf("unable to fetch %s of %s: %v", pin.Commit, s.remote, err)
+		}
+	}
+
+	content, err := s.show(pin.Commit, pin.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyPin(content, pin); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// sync clones the remote, or updates an existing clone to the head of
+// the default branch. A clone of a different remote is replaced.
+func (s *GitSource) sync() error {
+	if cache.Offline() {
+		return fmt.Errorf("unable to fetch %s: %w", s.remote, ErrOffline)
+	}
+
+	dir := s.dir()
+	if s.cloned() {
+		if origin, err := s.git("remote", "get-url", "origin"); err == nil && origin == s.remote {
+			if _, err := s.git("fetch", "--quiet", "--depth", "1", "--end-of-options", "origin", "HEAD"); err != nil {
+				return fmt.Errorf("unable to fetch %s: %v", s.remote, err)
+			}
+			if _, err := s.git("reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+				return err
+			}
+			return nil
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	if _, err := runGit("", "clone", "--quiet", "--depth", "1", "--end-of-options", s.remote, dir); err != nil {
+		return fmt.Errorf("unable to clone %s: %v", s.remote, err)
+	}
+	return nil
+}
+
+func (s *GitSource) cloned() bool {
+	_, err := os.Stat(filepath.Join(s.dir(), ".git"))
+	return err == nil
+}
+
+// fetchRef fetches a commit, branch or tag and returns its commit
+func (s *GitSource) fetchRef(ref string) (string, error) {
+	if err := checkRef(ref); err != nil {
+		return "", err
+	}
+	if cache.Offline() {
+		return "", ErrOffline
+	}
+	if !s.cloned() {
+		if err := s.sync(); err != nil {
+			return "", err
+		}
+	}
+	if _, err := s.git("fetch", "--quiet", "--depth", "1", "--end-of-options", "origin", ref); err != nil {
+		return "", err
+	}
+	return s.git("rev-parse", "FETCH_HEAD^{commit}")
+}
+
+// checkRef rejects refs that are neither a commit nor a well-formed
+// branch or tag name, so they cannot be taken for options by git
+func checkRef(ref string) error {
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid ref %q", ref)
+	}
+	if commitSHA.MatchString(ref) {
+		return nil
+	}
+	if _, err := runGit("", "check-ref-format", "--allow-onelevel", ref); err != nil {
+		return fmt.Errorf("invalid ref %q", ref)
+	}
+	return nil
+}
+
+// walk lists the templates in the working tree of the clone
+func (s *GitSource) walk() ([]Template, error) {
+	return walkTemplates(s.dir(), s.prefix)
+}
+
+// listCommit lists the templates at a fetched commit
+func (s *GitSource) listCommit(commit string) ([]Template, error) {
+	out, err := s.git("ls-tree", "-r", "--name-only", "--end-of-options", commit)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []Template
+	for _, file := range strings.Split(out, "\n") {
+		name := path.Base(file)
+		if !strings.HasSuffix(name, ".gitignore") || name == ".gitignore" {
+			continue
+		}
+		templates = append(templates, Template{Name: name, Path: file, Source: s.prefix})
+	}
+	return templates, nil
+}
+
+// show returns the content of a file at a commit
+func (s *GitSource) show(commit, filePath string) ([]byte, error) {
+	content, err := runGit(s.dir(), "show", "--end-of-options", commit+":"+filePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s at %s: %v", filePath, commit, err)
+	}
+	return content, nil
+}
+
+// git runs git in the clone and returns its trimmed output
+func (s *GitSource) git(args ...string) (string, error) {
+	out, err := runGit(s.dir(), args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// runGit runs git in dir and returns its output. Prompts for credentials
+// are disabled so an inaccessible remote fails instead of hanging.
+func runGit(dir string, args ...string) ([]byte, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
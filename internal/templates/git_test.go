@@ -0,0 +1,201 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRemote is a bare repository with a working copy to commit from
+type testRemote struct {
+	t    *testing.T
+	url  string
+	work string
+}
+
+func newTestRemote(t *testing.T) *testRemote {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	r := &testRemote{
+		t:    t,
+		url:  "file://" + filepath.ToSlash(filepath.Join(dir, "remote.git")),
+		work: filepath.Join(dir, "work"),
+	}
+	r.git("", "init", "--quiet", "--bare", filepath.Join(dir, "remote.git"))
+	r.git("", "clone", "--quiet", r.url, r.work)
+	return r
+}
+
+func (r *testRemote) git(dir string, args ...string) string {
+	r.t.Helper()
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	args = append([]string{"-c", "user.name=gignr", "-c", "user.email=gignr@example.com", "-c", "commit.gpgsign=false"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit writes files, removing those with empty content, and pushes a
+// commit to the remote
+func (r *testRemote) commit(files map[string]string) string {
+	r.t.Helper()
+	for name, content := range files {
+		p := filepath.Join(r.work, filepath.FromSlash(name))
+		if content == "" {
+			if err := os.Remove(p); err != nil {
+				r.t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	r.git(r.work, "add", "-A")
+	r.git(r.work, "commit", "--quiet", "-m", "update")
+	r.git(r.work, "push", "--quiet", "origin", "HEAD")
+	return r.git(r.work, "rev-parse", "HEAD")
+}
+
+func newTestGitSource(t *testing.T, remote *testRemote, nickname string) *GitSource {
+	t.Setenv("HOME", t.TempDir())
+	src, err := NewRepositorySource(nickname, RepositoryConfig{URL: remote.url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	git, ok := src.(*GitSource)
+	if !ok {
+		t.Fatalf("%s is served by %T, want a git source", remote.url, src)
+	}
+	return git
+}
+
+func TestGitSourceList(t *testing.T) {
+	remote := newTestRemote(t)
+	remote.commit(map[string]string{
+		"Go.gitignore":           "bin/\n",
+		"Global/macOS.gitignore": ".DS_Store\n",
+		"README.md":              "templates\n",
+	})
+	src := newTestGitSource(t, remote, "gitlist")
+
+	templates, err := src.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths := strings.Join(templatePaths(templates), ","); paths != "Global/macOS.gitignore,Go.gitignore" && paths != "Go.gitignore,Global/macOS.gitignore" {
+		t.Errorf("listed %s, want the two .gitignore files", paths)
+	}
+
+	content, err := src.Fetch("macOS")
+	if err != nil || string(content) != ".DS_Store\n" {
+		t.Errorf("Fetch = %q, %v", content, err)
+	}
+
+	// Refreshing pulls new commits and drops removed templates
+	remote.commit(map[string]string{
+		"Global/macOS.gitignore": "",
+		"Rust.gitignore":         "target/\n",
+	})
+	if err := src.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	templates, err = src.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths := strings.Join(templatePaths(templates), ","); paths != "Go.gitignore,Rust.gitignore" && paths != "Rust.gitignore,Go.gitignore" {
+		t.Errorf("listed %s after refresh, want Go and Rust", paths)
+	}
+}
+
+func TestGitSourcePin(t *testing.T) {
+	remote := newTestRemote(t)
+	first := remote.commit(map[string]string{"Go.gitignore": "bin/\n"})
+	remote.git(remote.work, "tag", "v1")
+	remote.git(remote.work, "push", "--quiet", "origin", "v1")
+	head := remote.commit(map[string]string{"Go.gitignore": "bin/\n*.test\n"})
+	src := newTestGitSource(t, remote, "gitpin")
+
+	content, pin, err := src.Resolve("Go", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Pin{Repo: remote.url, Path: "Go.gitignore", Commit: head, SHA256: ContentHash([]byte("bin/\n*.test\n"))}
+	if pin != want || string(content) != "bin/\n*.test\n" {
+		t.Errorf("Resolve = %q, %+v, want %+v", content, pin, want)
+	}
+
+	content, pin, err = src.Resolve("Go", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.Commit != first || string(content) != "bin/\n" {
+		t.Errorf("Resolve at v1 = %q, %+v, want commit %s", content, pin, first)
+	}
+
+	// The pinned commit is fetched again for a fresh clone
+	t.Setenv("HOME", t.TempDir())
+	remote.commit(map[string]string{"Go.gitignore": "changed\n"})
+	content, err = src.FetchPinned(pin)
+	if err != nil || string(content) != "bin/\n" {
+		t.Errorf("FetchPinned = %q, %v", content, err)
+	}
+
+	tampered := pin
+	tampered.SHA256 = ContentHash([]byte("other"))
+	if _, err := src.FetchPinned(tampered); err == nil || !strings.Contains(err.Error(), "does not match the pinned hash") {
+		t.Errorf("FetchPinned with a wrong hash = %v", err)
+	}
+
+	moved := pin
+	moved.Repo = "file:///elsewhere.git"
+	if _, err := src.FetchPinned(moved); !errors.Is(err, ErrStalePin) {
+		t.Errorf("FetchPinned of another remote = %v, want ErrStalePin", err)
+	}
+
+	if _, _, err := src.Resolve("Go", "missing"); err == nil {
+		t.Error("resolving a missing ref succeeded")
+	}
+}
+
+func TestGitSourceRejectsOptionRefs(t *testing.T) {
+	remote := newTestRemote(t)
+	head := remote.commit(map[string]string{"Go.gitignore": "bin/\n"})
+	src := newTestGitSource(t, remote, "gitrefs")
+
+	marker := filepath.Join(t.TempDir(), "injected")
+	for _, ref := range []string{"--upload-pack=touch " + marker + "; git-upload-pack", "-v", "a b", "main..other"} {
+		if _, _, err := src.Resolve("Go", ref); err == nil || !strings.Contains(err.Error(), "invalid ref") {
+			t.Errorf("Resolve at %q = %v, want an invalid ref error", ref, err)
+		}
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("a ref was run as an option of git fetch")
+	}
+
+	pin := Pin{Repo: remote.url, Path: "Go.gitignore", Commit: "--output=" + marker, SHA256: ContentHash([]byte("bin/\n"))}
+	if _, err := src.FetchPinned(pin); err == nil || !strings.Contains(err.Error(), "invalid pinned commit") {
+		t.Errorf("FetchPinned of an option = %v, want an invalid commit error", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("a pinned commit was run as an option of git")
+	}
+
+	if _, pin, err := src.Resolve("Go", head); err != nil || pin.Commit != head {
+		t.Errorf("Resolve at a commit = %+v, %v", pin, err)
+	}
+}
@@ -0,0 +1,119 @@
+package templates
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// gitea is a repository on a Gitea or Forgejo server, e.g. codeberg.org,
+// accessed through the v1 REST API
+type gitea struct {
+	apiBase string
+	host    string
+	owner   string
+	repo    string
+}
+
+// newGitea parses a repository URL, e.g. https://codeberg.org/owner/repo
+func newGitea(u *url.URL, apiURL string) (*gitea, error) {
+	parts := repoPath(u)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Gitea URL must have the form https://{host}/{owner}/{repo}")
+	}
+
+	if apiURL == "" {
+		apiURL = u.Scheme + "://" + u.Host + "/api/v1"
+	}
+	return &gitea{
+		apiBase: strings.TrimSuffix(apiURL, "/"),
+		host:    u.Host,
+		owner:   parts[0],
+		repo:    parts[1],
+	}, nil
+}
+
+func (g *gitea) repoID() string {
+	return g.host + "/" + g.owner + "/" + g.repo
+}
+
+func (g *gitea) repoURL() string {
+	return fmt.Sprintf("%s/repos/%s/%s", g.apiBase, url.PathEscape(g.owner), url.PathEscape(g.repo))
+}
+
+func (g *gitea) resolve(ref string) (string, error) {
+	if ref == "" {
+		var repo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if _, err := getJSON(g.repoURL(), &repo); err != nil {
+			return "", err
+		}
+		if repo.DefaultBranch == "" {
+			return "", fmt.Errorf("repository %s/%s has no default branch", g.owner, g.repo)
+		}
+		ref = repo.DefaultBranch
+	}
+
+	query := url.Values{
+		"sha":          {ref},
+		"limit":        {"1"},
+		"stat":         {"false"},
+		"verification": {"false"},
+		"files":        {"false"},
+	}
+	var commits []struct {
+		SHA string `json:"sha"`
+	}
+	if _, err := getJSON(g.repoURL()+"/commits?"+query.Encode(), &commits); err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("no commits found for %s", ref)
+	}
+	return commits[0].SHA, nil
+}
+
+func (g *gitea) files(commit string) ([]string, error) {
+	var files []string
+	for page := 1; ; page++ {
+		query := url.Values{
+			"recursive": {"true"},
+			"per_page":  {"1000"},
+			"page":      {strconv.Itoa(page)},
+		}
+
+		var tree struct {
+			Entries []struct {
+				Path string `json:"path"`
+				Type string `json:"type"`
+			} `json:"tree"`
+			Truncated bool `json:"truncated"`
+		}
+		if _, err := getJSON(g.repoURL()+"/git/trees/"+url.PathEscape(commit)+"?"+query.Encode(), &tree); err != nil {
+			return nil, err
+		}
+
+		for _, entry := range tree.Entries {
+			if entry.Type == "blob" {
+				files = append(files, entry.Path)
+			}
+		}
+		// Gitea reports further pages as a truncated tree
+		if !tree.Truncated || len(tree.Entries) == 0 {
+			return files, nil
+		}
+	}
+}
+
+func (g *gitea) rawURL(commit, filePath string) string {
+	return fmt.Sprintf("%s/raw/%s?ref=%s", g.repoURL(), escapePath(filePath), url.QueryEscape(commit))
+}
+
+func (g *gitea) authenticate(token string) error {
+	if token == "" {
+		return nil
+	}
+	return setAPIAuth(g.apiBase, "token "+token)
+}
@@ -0,0 +1,171 @@
+package templates
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/jasonuc/gignr/internal/httpclient"
+	"github.com/spf13/viper"
+)
+
+const defaultRawBase = "https://raw.githubusercontent.com"
+
+// githubAPI is a GitHub or GitHub Enterprise server, addressed by the
+// base URL of its REST API and the base URL raw files are served from
+type githubAPI struct {
+	client  *github.Client
+	rawBase string
+	// host is the web host of the server, e.g. ghe.corp. It is empty for
+	// public GitHub.
+	host string
+}
+
+// httpClient is used for all requests to template servers. It
+// authenticates requests to hosts a token was registered for, and makes
+// no requests at all while gignr is offline.
+var httpClient = httpclient.New(httpclient.WithTransport(func(base http.RoundTripper) http.RoundTripper {
+	return &offlineTransport{base: &tokenTransport{base: base}}
+}))
+
+// publicGitHub serves the built-in sources and repositories on github.com
+var publicGitHub = mustGitHubAPI("", "", "")
+
+var (
+	hostAuthMu sync.RWMutex
+	// hostAuth maps hosts to the Authorization header sent to them
+	hostAuth = map[string]string{}
+)
+
+// InitGitHubClient sets the token used for github.com. The API and raw
+// base URLs of public GitHub can be overridden with the github.api_url
+// and github.raw_url config keys, e.g. to point at a mirror.
+func InitGitHubClient(token string) error {
+	api, err := newGitHubAPI("", viper.GetString("github.api_url"), viper.GetString("github.raw_url"))
+	if err != nil {
+		return fmt.Errorf("invalid GitHub configuration: %w", err)
+	}
+
+	// The token is only sent to GitHub itself, not to an overriding server
+	for _, host := range []string{"github.com", "api.github.com", "raw.githubusercontent.com"} {
+		if err := setHostAuth(host, bearer(token)); err != nil {
+			return err
+		}
+	}
+	publicGitHub = api
+	return nil
+}
+
+// newGitHubAPI creates a client for a server. Empty base URLs default to
+// those of public GitHub.
+func newGitHubAPI(host, apiURL, rawURL string) (*githubAPI, error) {
+	client := github.NewClient(httpClient)
+	client.UserAgent = httpclient.UserAgent()
+
+	if apiURL != "" {
+		base, err := parseBaseURL(apiURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API URL %q: %w", apiURL, err)
+		}
+		// Set directly rather than through WithEnterpriseURLs, which
+		// would append /api/v3/ to servers that serve the API elsewhere
+		client.BaseURL = base
+		client.UploadURL = base
+	}
+
+	rawBase := defaultRawBase
+	if rawURL != "" {
+		base, err := parseBaseURL(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid raw URL %q: %w", rawURL, err)
+		}
+		rawBase = strings.TrimSuffix(base.String(), "/")
+	}
+
+	return &githubAPI{client: client, rawBase: rawBase, host: host}, nil
+}
+
+func mustGitHubAPI(host, apiURL, rawURL string) *githubAPI {
+	api, err := newGitHubAPI(host, apiURL, rawURL)
+	if err != nil {
+		panic(err)
+	}
+	return api
+}
+
+// authenticate sends token to the API and raw file hosts of the server
+func (g *githubAPI) authenticate(token string) error {
+	if err := setHostAuth(g.client.BaseURL.Hostname(), bearer(token)); err != nil {
+		return err
+	}
+	if u, err := url.Parse(g.rawBase); err == nil {
+		return setHostAuth(u.Hostname(), bearer(token))
+	}
+	return nil
+}
+
+// parseBaseURL parses an absolute http(s) URL and adds the trailing slash
+// go-github expects of base URLs
+func parseBaseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+		return nil, fmt.Errorf("must be an absolute http(s) URL")
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
+
+// setHostAuth sets the Authorization header sent with requests to host.
+// Credentials apply to every request to a host, so a host cannot be given
+// two different tokens.
+func setHostAuth(host, header string) error {
+	if host == "" || header == "" {
+		return nil
+	}
+	hostAuthMu.Lock()
+	defer hostAuthMu.Unlock()
+	if current, ok := hostAuth[host]; ok && current != header {
+		return fmt.Errorf("a different token is already used for %s, repositories on the same host must share a token", host)
+	}
+	hostAuth[host] = header
+	return nil
+}
+
+func authHeader(host string) string {
+	hostAuthMu.RLock()
+	defer hostAuthMu.RUnlock()
+	return hostAuth[host]
+}
+
+func bearer(token string) string {
+	if token == "" {
+		return ""
+	}
+	return "Bearer " + token
+}
+
+// tokenTransport authenticates requests to hosts a token was registered
+// for only, so tokens never reach other servers that templates are
+// downloaded from
+type tokenTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	header := authHeader(req.URL.Hostname())
+	if header == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", header)
+	return t.base.RoundTrip(req)
+}
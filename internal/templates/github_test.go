@@ -0,0 +1,334 @@
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasonuc/gignr/internal/cache"
+)
+
+// fakeGitHub serves the trees, commits and raw files of a single
+// repository the way the GitHub API and raw host do
+type fakeGitHub struct {
+	*httptest.Server
+
+	// commits maps refs to commits, and files maps commits to the
+	// contents of their files by path
+	commits map[string]string
+	files   map[string]map[string]string
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	f := &fakeGitHub{
+		commits: map[string]string{
+			"HEAD": "c2",
+			"v1":   "c1",
+		},
+		files: map[string]map[string]string{
+			"c1": {
+				"Go.gitignore": "bin/\n",
+				"README.md":    "templates\n",
+			},
+			"c2": {
+				"Go.gitignore":           "bin/\n*.test\n",
+				"Global/macOS.gitignore": ".DS_Store\n",
+				"README.md":              "templates\n",
+			},
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/o/r/git/trees/", f.tree)
+	mux.HandleFunc("/api/v3/repos/o/r/commits/", f.commit)
+	mux.HandleFunc("/raw/o/r/", f.raw)
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// resolve maps a ref or commit to a commit
+func (f *fakeGitHub) resolve(ref string) (string, bool) {
+	if commit, ok := f.commits[ref]; ok {
+		return commit, true
+	}
+	_, ok := f.files[ref]
+	return ref, ok
+}
+
+func (f *fakeGitHub) record(r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	conditional := ""
+	if r.Header.Get("If-None-Match") != "" {
+		conditional = " (conditional)"
+	}
+	f.requests = append(f.requests, r.URL.Path+conditional)
+}
+
+// take returns the requests made since the last call
+func (f *fakeGitHub) take() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+// serve answers with body, or 304 when the request carries its ETag
+func serve(w http.ResponseWriter, r *http.Request, body string) {
+	etag := fmt.Sprintf(`"%x"`, ContentHash([]byte(body))[:16])
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	fmt.Fprint(w, body)
+}
+
+func (f *fakeGitHub) tree(w http.ResponseWriter, r *http.Request) {
+	f.record(r)
+	commit, ok := f.resolve(strings.TrimPrefix(r.URL.Path, "/api/v3/repos/o/r/git/trees/"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	type entry struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	var tree struct {
+		SHA  string  `json:"sha"`
+		Tree []entry `json:"tree"`
+	}
+	tree.SHA = commit
+	for p := range f.files[commit] {
+		tree.Tree = append(tree.Tree, entry{Path: p, Type: "blob"})
+	}
+	body, _ := json.Marshal(tree)
+	serve(w, r, string(body))
+}
+
+func (f *fakeGitHub) commit(w http.ResponseWriter, r *http.Request) {
+	f.record(r)
+	commit, ok := f.resolve(strings.TrimPrefix(r.URL.Path, "/api/v3/repos/o/r/commits/"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprint(w, commit)
+}
+
+func (f *fakeGitHub) raw(w http.ResponseWriter, r *http.Request) {
+	f.record(r)
+	ref, filePath, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/raw/o/r/"), "/")
+	commit, ok := f.resolve(ref)
+	content, exists := f.files[commit][filePath]
+	if !ok || !exists {
+		http.NotFound(w, r)
+		return
+	}
+	serve(w, r, content)
+}
+
+// newTestGitHubSource creates a source for the repository served by f,
+// with the cache in a temporary home directory
+func newTestGitHubSource(t *testing.T, f *fakeGitHub, nickname string) *GitHubSource {
+	t.Setenv("HOME", t.TempDir())
+	src, err := newGitHubRepository(nickname, RepositoryConfig{
+		URL:    "https://ghe.test/o/r",
+		APIURL: f.URL + "/api/v3",
+		RawURL: f.URL + "/raw",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+// expire backdates the cached listing of a source
+func expire(t *testing.T, cacheFile string) {
+	t.Helper()
+	var cached TemplatesCache
+	if err := cache.LoadCache(cacheFile, &cached); err != nil {
+		t.Fatal(err)
+	}
+	cached.Updated = time.Now().Add(-2 * cache.TTL)
+	if err := cache.SaveCache(cacheFile, cached); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func templatePaths(templates []Template) []string {
+	var paths []string
+	for _, tmpl := range templates {
+		paths = append(paths, tmpl.Path)
+	}
+	return paths
+}
+
+func TestGitHubSourceList(t *testing.T) {
+	f := newFakeGitHub(t)
+	src := newTestGitHubSource(t, f, "ghlist")
+
+	templates, err := src.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := strings.Join(templatePaths(templates), ",")
+	if !strings.Contains(paths, "Go.gitignore") || !strings.Contains(paths, "Global/macOS.gitignore") || strings.Contains(paths, "README.md") {
+		t.Errorf("listed %s, want the two .gitignore files", paths)
+	}
+	if got := f.take(); len(got) != 1 || got[0] != "/api/v3/repos/o/r/git/trees/HEAD" {
+		t.Errorf("listing made requests %v, want a single recursive tree request", got)
+	}
+
+	// A fresh listing is served from the cache
+	if _, err := src.List(); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.take(); len(got) != 0 {
+		t.Errorf("cached listing made requests %v", got)
+	}
+
+	// An expired listing is served right away and revalidated with a
+	// conditional request
+	expire(t, src.cacheFile)
+	if _, err := src.List(); err != nil {
+		t.Fatal(err)
+	}
+	WaitForRevalidation(5 * time.Second)
+	if got := f.take(); len(got) != 1 || got[0] != "/api/v3/repos/o/r/git/trees/HEAD (conditional)" {
+		t.Errorf("revalidation made requests %v, want a conditional tree request", got)
+	}
+
+	var cached TemplatesCache
+	if err := cache.LoadCache(src.cacheFile, &cached); err != nil {
+		t.Fatal(err)
+	}
+	if cache.IsCacheExpired(cached.Updated) || len(cached.Templates) != 2 {
+		t.Errorf("revalidated listing has %d templates and was updated at %s", len(cached.Templates), cached.Updated)
+	}
+}
+
+func TestGitHubSourceRefreshDropsRemovedTemplates(t *testing.T) {
+	f := newFakeGitHub(t)
+	src := newTestGitHubSource(t, f, "ghrefresh")
+
+	if _, err := src.List(); err != nil {
+		t.Fatal(err)
+	}
+	delete(f.files["c2"], "Global/macOS.gitignore")
+
+	if err := src.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := src.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths := templatePaths(templates); len(paths) != 1 || paths[0] != "Go.gitignore" {
+		t.Errorf("listed %v after refresh, want [Go.gitignore]", paths)
+	}
+}
+
+func TestGitHubSourceFetch(t *testing.T) {
+	f := newFakeGitHub(t)
+	src := newTestGitHubSource(t, f, "ghfetch")
+
+	content, err := src.Fetch("macOS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != ".DS_Store\n" {
+		t.Errorf("fetched %q", content)
+	}
+	f.take()
+
+	// Expired content is revalidated and kept when unchanged
+	url := f.URL + "/raw/o/r/HEAD/Global/macOS.gitignore"
+	var entries map[string]cache.TemplateContentCache
+	if err := cache.LoadCache("template-content.json", &entries); err != nil {
+		t.Fatal(err)
+	}
+	entry := entries[url]
+	entry.Updated = time.Now().Add(-2 * cache.TTL)
+	entries[url] = entry
+	if err := cache.SaveCache("template-content.json", entries); err != nil {
+		t.Fatal(err)
+	}
+
+	fetched, err := WarmTemplateContent(url)
+	if err != nil || !fetched {
+		t.Fatalf("WarmTemplateContent = %v, %v", fetched, err)
+	}
+	if got := f.take(); len(got) != 1 || !strings.HasSuffix(got[0], "(conditional)") {
+		t.Errorf("revalidation made requests %v, want a conditional raw request", got)
+	}
+	if content, err := GetTemplateContent(url); err != nil || string(content) != ".DS_Store\n" {
+		t.Errorf("GetTemplateContent after 304 = %q, %v", content, err)
+	}
+	if got := f.take(); len(got) != 0 {
+		t.Errorf("revalidated content made requests %v", got)
+	}
+
+	if _, err := src.Fetch("Rust"); err == nil {
+		t.Error("fetching a missing template succeeded")
+	}
+}
+
+func TestGitHubSourcePin(t *testing.T) {
+	f := newFakeGitHub(t)
+	src := newTestGitHubSource(t, f, "ghpin")
+
+	content, pin, err := src.Resolve("Go", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Pin{Repo: "ghe.test/o/r", Path: "Go.gitignore", Commit: "c2", SHA256: ContentHash([]byte("bin/\n*.test\n"))}
+	if pin != want || string(content) != "bin/\n*.test\n" {
+		t.Errorf("Resolve = %q, %+v, want %+v", content, pin, want)
+	}
+
+	content, pin, err = src.Resolve("Go", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.Commit != "c1" || string(content) != "bin/\n" {
+		t.Errorf("Resolve at v1 = %q, %+v", content, pin)
+	}
+
+	// The pinned revision is fetched even after the branch moved on
+	f.commits["HEAD"] = "c3"
+	f.files["c3"] = map[string]string{"Go.gitignore": "changed\n"}
+	content, err = src.FetchPinned(pin)
+	if err != nil || string(content) != "bin/\n" {
+		t.Errorf("FetchPinned = %q, %v", content, err)
+	}
+
+	tampered := pin
+	tampered.SHA256 = ContentHash([]byte("other"))
+	if _, err := src.FetchPinned(tampered); err == nil || !strings.Contains(err.Error(), "does not match the pinned hash") {
+		t.Errorf("FetchPinned with a wrong hash = %v", err)
+	}
+
+	moved := pin
+	moved.Repo = "ghe.test/o/other"
+	if _, err := src.FetchPinned(moved); !errors.Is(err, ErrStalePin) {
+		t.Errorf("FetchPinned of another repository = %v, want ErrStalePin", err)
+	}
+
+	if _, _, err := src.Resolve("Go", "missing"); err == nil {
+		t.Error("resolving a missing ref succeeded")
+	}
+}
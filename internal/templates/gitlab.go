@@ -0,0 +1,106 @@
+package templates
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// gitLab is a project on gitlab.com or a self-hosted GitLab server,
+// accessed through the v4 REST API
+type gitLab struct {
+	apiBase string
+	host    string
+	project string
+}
+
+// newGitLab parses a project URL, e.g. https://gitlab.com/group/sub/project.
+// Projects may be nested in any number of groups.
+func newGitLab(u *url.URL, apiURL string) (*gitLab, error) {
+	parts := repoPath(u)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("GitLab URL must have the form https://{host}/{group}/{project}")
+	}
+	for _, part := range parts {
+		if part == "" || part == "-" {
+			return nil, fmt.Errorf("GitLab URL must point at a project, not a page within it")
+		}
+	}
+
+	if apiURL == "" {
+		apiURL = u.Scheme + "://" + u.Host + "/api/v4"
+	}
+	return &gitLab{
+		apiBase: strings.TrimSuffix(apiURL, "/"),
+		host:    u.Host,
+		project: strings.Join(parts, "/"),
+	}, nil
+}
+
+func (g *gitLab) repoID() string {
+	return g.host + "/" + g.project
+}
+
+func (g *gitLab) projectURL() string {
+	return g.apiBase + "/projects/" + url.PathEscape(g.project)
+}
+
+func (g *gitLab) resolve(ref string) (string, error) {
+	if ref == "" {
+		var project struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if _, err := getJSON(g.projectURL(), &project); err != nil {
+			return "", err
+		}
+		if project.DefaultBranch == "" {
+			return "", fmt.Errorf("project %s has no default branch", g.project)
+		}
+		ref = project.DefaultBranch
+	}
+
+	var commit struct {
+		ID string `json:"id"`
+	}
+	if _, err := getJSON(g.projectURL()+"/repository/commits/"+url.PathEscape(ref), &commit); err != nil {
+		return "", err
+	}
+	return commit.ID, nil
+}
+
+func (g *gitLab) files(commit string) ([]string, error) {
+	var files []string
+	for page := "1"; page != ""; {
+		query := url.Values{
+			"ref":       {commit},
+			"recursive": {"true"},
+			"per_page":  {"100"},
+			"page":      {page},
+		}
+
+		var entries []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+		}
+		header, err := getJSON(g.projectURL()+"/repository/tree?"+query.Encode(), &entries)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.Type == "blob" {
+				files = append(files, entry.Path)
+			}
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return files, nil
+}
+
+func (g *gitLab) rawURL(commit, filePath string) string {
+	return fmt.Sprintf("%s/repository/files/%s/raw?ref=%s", g.projectURL(), url.PathEscape(filePath), url.QueryEscape(commit))
+}
+
+func (g *gitLab) authenticate(token string) error {
+	return setAPIAuth(g.apiBase, bearer(token))
+}
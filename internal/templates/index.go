@@ -0,0 +1,145 @@
+package templates
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Index is a static catalog of templates, published as JSON:
+//
+//	{
+//	  "templates": [
+//	    {
+//	      "name": "Go",
+//	      "url": "go.gitignore",
+//	      "description": "Go modules and build output",
+//	      "sha256": "9f86d0..."
+//	    }
+//	  ]
+//	}
+//
+// URLs may be relative to the index. Downloads are checked against the
+// hash when one is given.
+type Index struct {
+	Templates []IndexEntry `json:"templates"`
+}
+
+// IndexEntry is a template listed in an Index
+type IndexEntry struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	SHA256      string `json:"sha256,omitempty"`
+}
+
+// IsIndexURL reports whether a URL points at an index file
+func IsIndexURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return false
+	}
+	return strings.HasSuffix(parsed.Path, ".json")
+}
+
+// IndexSource serves templates listed in an index file on a web server
+type IndexSource struct {
+	prefix    string
+	indexURL  *url.URL
+	cacheFile string
+}
+
+func newIndexSource(nickname, indexURL string) (*IndexSource, error) {
+	u, err := url.Parse(indexURL)
+	if err != nil || !IsIndexURL(indexURL) {
+		return nil, fmt.Errorf("index URL must be an http(s) URL of a .json file")
+	}
+
+	return &IndexSource{
+		prefix:    nickname,
+		indexURL:  u,
+		cacheFile: fmt.Sprintf("%s.json", nickname),
+	}, nil
+}
+
+func (s *IndexSource) Prefix() string      { return s.prefix }
+func (s *IndexSource) DisplayName() string { return s.prefix }
+
+func (s *IndexSource) List() ([]Template, error) {
+	return listCached(s.cacheFile, s.fetchIndex)
+}
+
+func (s *IndexSource) Fetch(name string) ([]byte, error) {
+	templates, err := s.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch templates from %s: %v", s.prefix, err)
+	}
+
+	tmpl, err := FindTemplate(name, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := GetTemplateContent(tmpl.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch content: %v", err)
+	}
+
+	if tmpl.SHA256 != "" {
+		if hash := ContentHash(content); !strings.EqualFold(hash, tmpl.SHA256) {
+			return nil, fmt.Errorf("content of %s does not match the hash in the index (got %s, want %s)", tmpl.DownloadURL, hash, tmpl.SHA256)
+		}
+	}
+	return content, nil
+}
+
+// Refresh re-fetches the index and replaces the cached listing
+func (s *IndexSource) Refresh() error {
+	templates, err := s.fetchIndex()
+	if err != nil {
+		return err
+	}
+
+	replaceTemplatesCache(s.cacheFile, templates)
+	return nil
+}
+
+// HasGitignoreTemplates reports whether the index lists any templates
+func (s *IndexSource) HasGitignoreTemplates() (bool, error) {
+	templates, err := s.fetchIndex()
+	if err != nil {
+		return false, err
+	}
+	return len(templates) > 0, nil
+}
+
+// fetchIndex downloads the index and turns its entries into templates
+func (s *IndexSource) fetchIndex() ([]Template, error) {
+	var index Index
+	if _, err := getJSON(s.indexURL.String(), &index); err != nil {
+		return nil, fmt.Errorf("failed to fetch index: %w", err)
+	}
+
+	templates := make([]Template, 0, len(index.Templates))
+	for i, entry := range index.Templates {
+		if entry.Name == "" || entry.URL == "" {
+			return nil, fmt.Errorf("invalid index %s: entry %d needs a name and a url", s.indexURL, i+1)
+		}
+
+		ref, err := url.Parse(entry.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %s: bad url for %s: %v", s.indexURL, entry.Name, err)
+		}
+
+		name := strings.TrimSuffix(entry.Name, ".gitignore")
+		templates = append(templates, Template{
+			Name:        name + ".gitignore",
+			Path:        name,
+			DownloadURL: s.indexURL.ResolveReference(ref).String(),
+			Source:      s.prefix,
+			Description: entry.Description,
+			SHA256:      entry.SHA256,
+		})
+	}
+	return templates, nil
+}
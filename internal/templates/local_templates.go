@@ -3,17 +3,52 @@ package templates
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
-func GetLocalTemplate(name string) ([]byte, error) {
+// LocalSource serves templates saved with `gignr save`. It is addressed
+// without a prefix.
+type LocalSource struct{}
+
+func (s *LocalSource) Prefix() string      { return "" }
+func (s *LocalSource) DisplayName() string { return "Local" }
+
+func (s *LocalSource) List() ([]Template, error) {
+	storagePath := localStoragePath()
+	files, err := os.ReadDir(storagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]Template, 0, len(files))
+	for _, f := range files {
+		if name := f.Name(); !f.IsDir() && strings.HasSuffix(name, ".gitignore") {
+			templates = append(templates, Template{
+				Name:   name,
+				Path:   filepath.Join(storagePath, name),
+				Source: s.DisplayName(),
+			})
+		}
+	}
+	return templates, nil
+}
+
+func (s *LocalSource) Fetch(name string) ([]byte, error) {
+	return GetLocalTemplate(name)
+}
+
+func localStoragePath() string {
 	storagePath := viper.GetString("templates.storage_path")
 	if storagePath == "" {
 		storagePath = filepath.Join(os.Getenv("HOME"), ".config/gignr/templates")
 	}
+	return storagePath
+}
 
-	templatePath := filepath.Join(storagePath, name+".gitignore")
+func GetLocalTemplate(name string) ([]byte, error) {
+	templatePath := filepath.Join(localStoragePath(), name+".gitignore")
 	return os.ReadFile(templatePath)
 }
 
@@ -0,0 +1,38 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/jasonuc/gignr/internal/cache"
+)
+
+// ErrOffline is returned for requests that need the network while gignr
+// is offline
+var ErrOffline = errors.New("gignr is offline")
+
+// offlineTransport fails every request while gignr is offline, so no
+// source reaches the network regardless of how it fetches templates
+type offlineTransport struct {
+	base http.RoundTripper
+}
+
+func (t *offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if cache.Offline() {
+		return nil, ErrOffline
+	}
+	return t.base.RoundTrip(req)
+}
+
+// isUnreachable reports whether err means the server could not be
+// reached at all, rather than that it answered with an error
+func isUnreachable(err error) bool {
+	if errors.Is(err, ErrOffline) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr)
+}
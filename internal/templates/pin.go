@@ -0,0 +1,143 @@
+package templates
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jasonuc/gignr/internal/cache"
+	"github.com/jasonuc/gignr/internal/utils"
+)
+
+// Pin identifies the exact revision a template was fetched at
+type Pin struct {
+	Repo   string `json:"repo"`
+	Path   string `json:"path"`
+	Commit string `json:"commit"`
+	SHA256 string `json:"sha256"`
+}
+
+// Pinnable is implemented by sources that can fetch a template at an
+// immutable revision, so the content can be reproduced later
+type Pinnable interface {
+	// Resolve fetches the content of the named template at ref, or at
+	// the head of the default branch when ref is empty, along with the
+	// revision it came from
+	Resolve(name, ref string) ([]byte, Pin, error)
+	// FetchPinned fetches the content recorded by pin and checks it
+	// against the recorded hash
+	FetchPinned(pin Pin) ([]byte, error)
+}
+
+// ErrStalePin is returned by FetchPinned when a pin was recorded for a
+// different repository than the one the source now points at
+var ErrStalePin = errors.New("pinned revision belongs to a different repository")
+
+// ContentHash returns the hex encoded SHA-256 of template content
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *GitHubSource) Resolve(name, ref string) ([]byte, Pin, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	commit, _, err := s.server().client.Repositories.GetCommitSHA1(context.Background(), s.owner, s.repo, ref, "")
+	if err != nil {
+		// When GitHub cannot be reached the head is the commit of the
+		// embedded snapshot. Errors returned by GitHub itself, such as a
+		// rate limit or a bad token, are reported instead.
+		if ref == "HEAD" && isUnreachable(err) {
+			if content, pin, ok, err := s.resolveSnapshot(name); ok {
+				if err == nil {
+					utils.PrintWarning(fmt.Sprintf("%s could not be reached, %s was pinned to the embedded snapshot from %s. Run with --upgrade once online to pin the latest revision.",
+						s.repoID(), name, loadSnapshot().Generated.Format("2006-01-02")))
+				}
+				return content, pin, err
+			}
+		}
+		return nil, Pin{}, s.withSnapshotHint(fmt.Errorf("unable to resolve %s of %s: %w", ref, s.repoID(), err))
+	}
+
+	var templates []Template
+	if ref == "HEAD" {
+		templates, err = s.List()
+	} else {
+		templates, err = s.listAt(commit)
+	}
+	if err != nil {
+		return nil, Pin{}, fmt.Errorf("unable to fetch templates from %s: %v", s.prefix, err)
+	}
+
+	tmpl, err := FindTemplate(name, templates)
+	if err != nil {
+		return nil, Pin{}, err
+	}
+
+	content, err := GetTemplateContent(s.server().rawURL(s.owner, s.repo, commit, tmpl.Path))
+	if err != nil {
+		return nil, Pin{}, fmt.Errorf("unable to fetch content: %v", err)
+	}
+
+	return content, Pin{
+		Repo:   s.repoID(),
+		Path:   tmpl.Path,
+		Commit: commit,
+		SHA256: ContentHash(content),
+	}, nil
+}
+
+// listAt lists the templates of the repository at a commit. The listing
+// of a commit never changes, so it is cached under its own file.
+func (s *GitHubSource) listAt(commit string) ([]Template, error) {
+	cacheFile := fmt.Sprintf("%s@%s.json", strings.TrimSuffix(s.cacheFile, ".json"), commit)
+	if templates, err := LoadCachedTemplates(cacheFile); err == nil {
+		return s.filter(templates), nil
+	}
+
+	templates, _, err := s.server().listTemplates(s.owner, s.repo, s.path, commit, cache.Validators{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch templates: %w", err)
+	}
+
+	SaveTemplatesToCache(cacheFile, templates)
+	return s.filter(templates), nil
+}
+
+func (s *GitHubSource) FetchPinned(pin Pin) ([]byte, error) {
+	if !strings.EqualFold(pin.Repo, s.repoID()) {
+		return nil, ErrStalePin
+	}
+
+	content, err := GetTemplateContent(s.server().rawURL(s.owner, s.repo, pin.Commit, pin.Path))
+	if err != nil {
+		var ok bool
+		if content, ok = s.pinnedSnapshotContent(pin); !ok {
+			return nil, fmt.Errorf("unable to fetch content: %v", err)
+		}
+	}
+
+	if err := verifyPin(content, pin); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// verifyPin checks fetched content against the hash recorded in pin
+func verifyPin(content []byte, pin Pin) error {
+	if hash := ContentHash(content); hash != pin.SHA256 {
+		return fmt.Errorf("content of %s at %s does not match the pinned hash (got %s, want %s)", pin.Path, pin.Commit, hash, pin.SHA256)
+	}
+	return nil
+}
+
+// rawURL builds the download URL of a file at a given ref
+func (g *githubAPI) rawURL(owner, repo, ref, filePath string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", g.rawBase, owner, repo, url.PathEscape(ref), escapePath(filePath))
+}
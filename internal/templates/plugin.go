@@ -0,0 +1,232 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jasonuc/gignr/internal/cache"
+)
+
+// PluginPrefix is the name prefix of executables on PATH that provide
+// templates, e.g. gignr-source-wiki serves templates as wiki:<name>
+const PluginPrefix = "gignr-source-"
+
+// PluginProtocolVersion is sent with every request so plugins can reject
+// versions they do not understand
+const PluginProtocolVersion = 1
+
+// pluginTimeout bounds how long a plugin may take to answer a request
+const pluginTimeout = 30 * time.Second
+
+// PluginRequest is written as JSON to the standard input of a plugin. A
+// plugin is started once per request.
+//
+//	{"version": 1, "method": "list"}
+//	{"version": 1, "method": "fetch", "name": "Go"}
+type PluginRequest struct {
+	Version int    `json:"version"`
+	Method  string `json:"method"`
+	Name    string `json:"name,omitempty"`
+}
+
+// PluginResponse is read as JSON from the standard output of a plugin.
+// "list" answers with templates, "fetch" with content. A plugin reports
+// failures with error instead of templates or content.
+//
+//	{"templates": [{"name": "Go", "description": "Go modules"}]}
+//	{"content": "bin/\n*.test\n"}
+//	{"error": "template Go not found"}
+type PluginResponse struct {
+	Templates []PluginTemplate `json:"templates,omitempty"`
+	Content   *string          `json:"content,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// PluginTemplate is a template listed by a plugin
+type PluginTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PluginSource serves templates from an external executable speaking the
+// plugin protocol
+type PluginSource struct {
+	prefix    string
+	path      string
+	cacheFile string
+}
+
+// DiscoverPlugins finds the gignr-source-<name> executables on PATH. As
+// with command lookup, the first directory providing a name wins.
+// Relative and empty PATH entries are skipped, so running gignr inside a
+// checkout never starts executables from it.
+func DiscoverPlugins() []*PluginSource {
+	seen := map[string]bool{}
+	var plugins []*PluginSource
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok || seen[name] {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+
+			seen[name] = true
+			plugins = append(plugins, newPluginSource(name, path))
+		}
+	}
+	return plugins
+}
+
+func newPluginSource(name, path string) *PluginSource {
+	return &PluginSource{
+		prefix:    name,
+		path:      path,
+		cacheFile: fmt.Sprintf("plugin-%s.json", name),
+	}
+}
+
+// pluginName returns the prefix served by a plugin executable, which
+// must not contain characters used in template arguments
+func pluginName(file string) (string, bool) {
+	name, ok := strings.CutPrefix(file, PluginPrefix)
+	if !ok {
+		return "", false
+	}
+	if runtime.GOOS == "windows" {
+		name = strings.TrimSuffix(strings.ToLower(name), ".exe")
+	}
+	if name == "" || strings.ContainsAny(name, ":@/\\ \t") {
+		return "", false
+	}
+	return name, true
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return info.Mode().Perm()&0111 != 0
+}
+
+func (s *PluginSource) Prefix() string      { return s.prefix }
+func (s *PluginSource) DisplayName() string { return s.prefix }
+
+// Path returns the location of the plugin executable
+func (s *PluginSource) Path() string { return s.path }
+
+func (s *PluginSource) List() ([]Template, error) {
+	return listCached(s.cacheFile, s.list)
+}
+
+func (s *PluginSource) Fetch(name string) ([]byte, error) {
+	resp, err := s.call(PluginRequest{Method: "fetch", Name: name})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Content == nil {
+		return nil, fmt.Errorf("plugin %s returned no content for %s", s.path, name)
+	}
+	return []byte(*resp.Content), nil
+}
+
+// Refresh asks the plugin for its templates again and replaces the
+// cached listing
+func (s *PluginSource) Refresh() error {
+	templates, err := s.list()
+	if err != nil {
+		return err
+	}
+
+	replaceTemplatesCache(s.cacheFile, templates)
+	return nil
+}
+
+func (s *PluginSource) list() ([]Template, error) {
+	resp, err := s.call(PluginRequest{Method: "list"})
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]Template, 0, len(resp.Templates))
+	for _, t := range resp.Templates {
+		name := strings.TrimSuffix(t.Name, ".gitignore")
+		if name == "" {
+			return nil, fmt.Errorf("plugin %s listed a template without a name", s.path)
+		}
+		templates = append(templates, Template{
+			Name:        name + ".gitignore",
+			Path:        name,
+			Source:      s.prefix,
+			Description: t.Description,
+		})
+	}
+	return templates, nil
+}
+
+// call runs the plugin with a request and decodes its response. Plugins
+// may use the network, so they are not started while gignr is offline.
+func (s *PluginSource) call(req PluginRequest) (PluginResponse, error) {
+	if cache.Offline() {
+		return PluginResponse{}, fmt.Errorf("plugin %s was not started: %w", s.path, ErrOffline)
+	}
+
+	req.Version = PluginProtocolVersion
+	input, err := json.Marshal(req)
+	if err != nil {
+		return PluginResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.path)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return PluginResponse{}, fmt.Errorf("plugin %s did not answer within %s", s.path, pluginTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return PluginResponse{}, fmt.Errorf("plugin %s failed: %s", s.path, msg)
+		}
+		return PluginResponse{}, fmt.Errorf("plugin %s failed: %v", s.path, err)
+	}
+
+	var resp PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return PluginResponse{}, fmt.Errorf("plugin %s returned an invalid response: %v", s.path, err)
+	}
+	if resp.Error != "" {
+		return PluginResponse{}, fmt.Errorf("%s", resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,129 @@
+package templates
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jasonuc/gignr/internal/snapshot"
+	"github.com/jasonuc/gignr/internal/utils"
+)
+
+//go:generate go run ./snapshotgen -o snapshot.json.gz
+
+// snapshot.json.gz is written by go generate and committed, so that go
+// install and source builds embed the catalog too
+//
+//go:embed snapshot.json.gz
+var snapshotData []byte
+
+var (
+	snapshotOnce sync.Once
+	embedded     snapshot.Snapshot
+)
+
+// loadSnapshot decodes the embedded snapshot on first use. A damaged
+// snapshot is treated as empty.
+func loadSnapshot() snapshot.Snapshot {
+	snapshotOnce.Do(func() {
+		embedded, _ = snapshot.Decode(bytes.NewReader(snapshotData))
+	})
+	return embedded
+}
+
+// snapshotRepo returns the embedded copy of the repository. Only
+// repositories on public GitHub are embedded.
+func (s *GitHubSource) snapshotRepo() (snapshot.Repo, bool) {
+	if s.api != nil {
+		return snapshot.Repo{}, false
+	}
+	repo, ok := loadSnapshot().Repos[s.owner+"/"+s.repo]
+	return repo, ok && len(repo.Files) > 0
+}
+
+// snapshotTemplates lists the templates of the repository from the
+// embedded snapshot
+func (s *GitHubSource) snapshotTemplates() ([]Template, bool) {
+	repo, ok := s.snapshotRepo()
+	if !ok {
+		return nil, false
+	}
+
+	var templates []Template
+	for p := range repo.Files {
+		if s.path != "" && !strings.HasPrefix(p, s.path+"/") {
+			continue
+		}
+
+		downloadURL := publicGitHub.rawURL(s.owner, s.repo, "HEAD", p)
+		templates = append(templates, Template{
+			Name:        path.Base(p),
+			Path:        p,
+			DownloadURL: downloadURL,
+			Source:      utils.DetectSource(downloadURL),
+		})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Path < templates[j].Path })
+	return templates, true
+}
+
+// snapshotContent returns the embedded content of a template
+func (s *GitHubSource) snapshotContent(filePath string) ([]byte, bool) {
+	repo, ok := s.snapshotRepo()
+	if !ok {
+		return nil, false
+	}
+	content, ok := repo.Files[filePath]
+	return []byte(content), ok
+}
+
+// resolveSnapshot resolves a template at the commit of the embedded
+// snapshot. ok is false when the repository is not embedded.
+func (s *GitHubSource) resolveSnapshot(name string) (content []byte, pin Pin, ok bool, err error) {
+	repo, ok := s.snapshotRepo()
+	if !ok {
+		return nil, Pin{}, false, nil
+	}
+
+	templates, _ := s.snapshotTemplates()
+	tmpl, err := FindTemplate(name, s.filter(templates))
+	if err != nil {
+		return nil, Pin{}, true, err
+	}
+
+	content = []byte(repo.Files[tmpl.Path])
+	return content, Pin{
+		Repo:   s.repoID(),
+		Path:   tmpl.Path,
+		Commit: repo.Commit,
+		SHA256: ContentHash(content),
+	}, true, nil
+}
+
+// pinnedSnapshotContent returns the embedded content of a template
+// pinned at the commit of the snapshot
+func (s *GitHubSource) pinnedSnapshotContent(pin Pin) ([]byte, bool) {
+	repo, ok := s.snapshotRepo()
+	if !ok || repo.Commit != pin.Commit {
+		return nil, false
+	}
+	return s.snapshotContent(pin.Path)
+}
+
+// withSnapshotHint points out that a built-in repository could have been
+// served without a network, had the snapshot been generated before this
+// build
+func (s *GitHubSource) withSnapshotHint(err error) error {
+	if s.api != nil || !slices.Contains(snapshot.Repos, s.owner+"/"+s.repo) {
+		return err
+	}
+	if _, ok := s.snapshotRepo(); ok {
+		return err
+	}
+	return fmt.Errorf("%w (this build of gignr embeds no templates, generate them with go generate ./internal/templates)", err)
+}
@@ -0,0 +1,117 @@
+// snapshotgen downloads the repositories of the built-in sources and
+// writes the snapshot embedded in gignr. It runs through go generate in
+// internal/templates, and the result is committed. Set GITHUB_TOKEN to
+// avoid rate limits.
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/jasonuc/gignr/internal/snapshot"
+)
+
+func main() {
+	out := flag.String("o", "snapshot.json.gz", "output file")
+	flag.Parse()
+
+	client := github.NewClient(nil)
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		client = client.WithAuthToken(token)
+	}
+
+	snap := snapshot.Snapshot{
+		Generated: time.Now().UTC(),
+		Repos:     map[string]snapshot.Repo{},
+	}
+	for _, name := range snapshot.Repos {
+		repo, err := download(client, name)
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		if len(repo.Files) == 0 {
+			log.Fatalf("%s: no templates found", name)
+		}
+		snap.Repos[name] = repo
+		log.Printf("%s@%s: %d templates", name, repo.Commit[:7], len(repo.Files))
+	}
+
+	var buf bytes.Buffer
+	if err := snapshot.Encode(&buf, snap); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// download fetches the head of the default branch of a repository and
+// returns its named `.gitignore` files
+func download(client *github.Client, name string) (snapshot.Repo, error) {
+	owner, repo, _ := strings.Cut(name, "/")
+	ctx := context.Background()
+
+	commit, _, err := client.Repositories.GetCommitSHA1(ctx, owner, repo, "HEAD", "")
+	if err != nil {
+		return snapshot.Repo{}, err
+	}
+
+	link, _, err := client.Repositories.GetArchiveLink(ctx, owner, repo, github.Tarball, &github.RepositoryContentGetOptions{Ref: commit}, 3)
+	if err != nil {
+		return snapshot.Repo{}, err
+	}
+
+	resp, err := http.Get(link.String())
+	if err != nil {
+		return snapshot.Repo{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return snapshot.Repo{}, fmt.Errorf("failed to download %s: %s", link, resp.Status)
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return snapshot.Repo{}, err
+	}
+	defer gz.Close()
+
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return snapshot.Repo{}, err
+		}
+
+		// Entries are stored under a <owner>-<repo>-<sha> directory
+		_, filePath, _ := strings.Cut(hdr.Name, "/")
+		base := path.Base(filePath)
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(base, ".gitignore") || base == ".gitignore" {
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return snapshot.Repo{}, err
+		}
+		files[filePath] = string(content)
+	}
+
+	return snapshot.Repo{Commit: commit, Files: files}, nil
+}
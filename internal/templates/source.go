@@ -0,0 +1,196 @@
+package templates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Source is a provider of `.gitignore` templates addressed by a prefix,
+// e.g. "gh" in "gh:Go".
+type Source interface {
+	// Prefix is the identifier used before the colon in template arguments.
+	Prefix() string
+	// DisplayName is the human readable name shown in the search TUI.
+	DisplayName() string
+	// List returns every template the source provides.
+	List() ([]Template, error)
+	// Fetch returns the content of the named template.
+	Fetch(name string) ([]byte, error)
+}
+
+// Refresher is implemented by sources whose listing can be re-fetched,
+// bypassing any cached copy.
+type Refresher interface {
+	Refresh() error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Source
+	builtins   = map[string]bool{}
+	// hidden sources can be looked up but are not listed, since they
+	// cannot enumerate their templates
+	hidden = map[string]Source{
+		GistPrefix: &GistSource{prefix: GistPrefix},
+	}
+)
+
+func init() {
+	for _, src := range builtinSources() {
+		builtins[src.Prefix()] = true
+		Register(src)
+	}
+	for prefix := range hidden {
+		builtins[prefix] = true
+	}
+}
+
+func builtinSources() []Source {
+	return []Source{
+		&GitHubSource{
+			prefix: "tt", displayName: "TopTal",
+			owner: "toptal", repo: "gitignore", path: "templates",
+			cacheFile: "toptal.json",
+		},
+		&GitHubSource{
+			prefix: "gh", displayName: "GitHub",
+			owner: "github", repo: "gitignore",
+			cacheFile: "github.json",
+			match:     inDirectory(""),
+		},
+		&GitHubSource{
+			prefix: "ghc", displayName: "GitHub Community",
+			owner: "github", repo: "gitignore",
+			cacheFile: "github.json",
+			match:     underDirectory("community"),
+		},
+		&GitHubSource{
+			prefix: "ghg", displayName: "GitHub Global",
+			owner: "github", repo: "gitignore",
+			cacheFile: "github.json",
+			match:     underDirectory("Global"),
+		},
+		&LocalSource{},
+	}
+}
+
+// Register adds a source to the registry, replacing any source that
+// already uses the same prefix.
+func Register(src Source) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for i, existing := range registry {
+		if existing.Prefix() == src.Prefix() {
+			registry[i] = src
+			return
+		}
+	}
+	registry = append(registry, src)
+}
+
+// Lookup returns the source registered for the given prefix
+func Lookup(prefix string) (Source, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, src := range registry {
+		if src.Prefix() == prefix {
+			return src, nil
+		}
+	}
+	if src, ok := hidden[prefix]; ok {
+		return src, nil
+	}
+	return nil, fmt.Errorf("unknown template prefix or missing repository: %s", prefix)
+}
+
+// Sources returns all registered sources in registration order
+func Sources() []Source {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return append([]Source(nil), registry...)
+}
+
+// IsReservedPrefix reports whether a prefix belongs to a built-in source
+// and therefore cannot be used as a repository nickname.
+func IsReservedPrefix(prefix string) bool {
+	return builtins[strings.ToLower(prefix)]
+}
+
+// ReservedPrefixes returns the prefixes of the built-in sources
+func ReservedPrefixes() []string {
+	var prefixes []string
+	for prefix := range builtins {
+		if prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// LoadCustomSources registers a source for every user-added repository
+// in the config. Repositories that cannot be turned into a source are
+// skipped and reported in the returned errors.
+func LoadCustomSources() []error {
+	repos := LoadCustomRepositories()
+	// Sorted so that the same repository is reported when two of them
+	// conflict
+	nicknames := make([]string, 0, len(repos))
+	for nickname := range repos {
+		nicknames = append(nicknames, nickname)
+	}
+	sort.Strings(nicknames)
+
+	var errs []error
+	for _, nickname := range nicknames {
+		src, err := NewRepositorySource(nickname, repos[nickname])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid repository for prefix %s: %w", nickname, err))
+			continue
+		}
+		Register(src)
+	}
+	return errs
+}
+
+// LoadPluginSources registers a source for every plugin found on PATH.
+// Plugins cannot take the prefix of a built-in source or of a
+// repository in the config; those are skipped and reported in the
+// returned errors.
+func LoadPluginSources() []error {
+	repos := LoadCustomRepositories()
+
+	var errs []error
+	for _, plugin := range DiscoverPlugins() {
+		prefix := plugin.Prefix()
+		if _, ok := repos[strings.ToLower(prefix)]; ok || IsReservedPrefix(prefix) {
+			errs = append(errs, fmt.Errorf("ignoring plugin %s: prefix %s is already in use", plugin.Path(), prefix))
+			continue
+		}
+		Register(plugin)
+	}
+	return errs
+}
+
+// FindTemplate looks up a template by name, preferring an exact match
+// over a case-insensitive one.
+func FindTemplate(templateName string, templates []Template) (Template, error) {
+	for _, tmpl := range templates {
+		if tmpl.Name == templateName+".gitignore" {
+			return tmpl, nil
+		}
+	}
+
+	for _, tmpl := range templates {
+		if strings.EqualFold(tmpl.Name, templateName+".gitignore") {
+			return tmpl, nil
+		}
+	}
+
+	return Template{}, fmt.Errorf("template %s not found", templateName)
+}
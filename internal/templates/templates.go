@@ -2,13 +2,17 @@ package templates
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"path"
 	"strings"
-	"sync"
 
 	"github.com/google/go-github/v57/github"
+	"github.com/jasonuc/gignr/internal/auth"
+	"github.com/jasonuc/gignr/internal/cache"
 	"github.com/jasonuc/gignr/internal/utils"
-	"golang.org/x/oauth2"
 )
 
 type Type string
@@ -23,104 +27,356 @@ type Template struct {
 	Path        string
 	DownloadURL string
 	Source      string
+	// Description and SHA256 are only known for templates from an index
+	Description string `json:",omitempty"`
+	SHA256      string `json:",omitempty"`
 }
 
-var githubClient *github.Client
+// HasGitignoreTemplates reports whether the root of the repository holds
+// named `.gitignore` files, e.g. python.gitignore
+func (s *GitHubSource) HasGitignoreTemplates() (bool, error) {
+	_, contents, resp, err := s.server().client.Repositories.GetContents(context.Background(), s.owner, s.repo, "", nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, fmt.Errorf("repository %s not found or not accessible with the current token", s.repoID())
+		}
+		return false, fmt.Errorf("failed to fetch repository contents: %v", err)
+	}
 
-func InitGitHubClient(token string) {
-	if token == "" {
-		githubClient = github.NewClient(nil)
-		return
+	for _, item := range contents {
+		if item.GetType() == string(TypeFile) && strings.HasSuffix(item.GetName(), ".gitignore") && item.GetName() != ".gitignore" {
+			return true, nil
+		}
 	}
+	return false, nil
+}
 
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(context.Background(), ts)
-	githubClient = github.NewClient(tc)
+// GitHubSource serves templates stored as `.gitignore` files in a
+// GitHub repository.
+type GitHubSource struct {
+	prefix      string
+	displayName string
+	owner       string
+	repo        string
+	path        string
+	cacheFile   string
+	match       func(Template) bool
+	// api is the server hosting the repository, public GitHub when nil
+	api *githubAPI
 }
 
-func FetchTemplates(owner, repo, path, sourceID string) ([]Template, error) {
-	if templates, err := LoadCachedTemplates(getCacheFileName(owner, sourceID)); err == nil {
-		return templates, nil
+// newGitHubRepository creates a source for a user-added GitHub
+// repository. Repositories on hosts other than github.com are served from
+// a GitHub Enterprise server, at https://<host>/api/v3 and
+// https://<host>/raw unless other base URLs are configured.
+func newGitHubRepository(nickname string, cfg RepositoryConfig) (*GitHubSource, error) {
+	owner, repo, err := utils.ExtractRepoDetails(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &GitHubSource{
+		prefix:      nickname,
+		displayName: nickname,
+		owner:       owner,
+		repo:        repo,
+		cacheFile:   fmt.Sprintf("%s.json", nickname),
+	}
+
+	u, _ := url.Parse(cfg.URL)
+	if u.Hostname() == auth.DefaultHost && cfg.APIURL == "" && cfg.RawURL == "" {
+		// Requests to github.com are authenticated with the GitHub token
+		if cfg.Token != "" {
+			return nil, fmt.Errorf("repositories on github.com use the GitHub token, set github.token or GITHUB_TOKEN instead of token")
+		}
+		return src, nil
+	}
+
+	apiURL, rawURL := cfg.APIURL, cfg.RawURL
+	if u.Hostname() != auth.DefaultHost {
+		origin := u.Scheme + "://" + u.Host
+		if apiURL == "" {
+			apiURL = origin + "/api/v3"
+		}
+		if rawURL == "" {
+			rawURL = origin + "/raw"
+		}
 	}
 
-	templates, err := fetchFromGitHub(owner, repo, path)
+	src.api, err = newGitHubAPI(u.Host, apiURL, rawURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch templates: %w", err)
+		return nil, err
+	}
+	token := cfg.Token
+	if token == "" {
+		token = auth.GitHubToken(u.Hostname())
+	}
+	if err := src.api.authenticate(token); err != nil {
+		return nil, err
 	}
+	return src, nil
+}
 
-	SaveTemplatesToCache(getCacheFileName(owner, sourceID), templates)
-	return templates, nil
+func (s *GitHubSource) Prefix() string      { return s.prefix }
+func (s *GitHubSource) DisplayName() string { return s.displayName }
+
+// server returns the server hosting the repository
+func (s *GitHubSource) server() *githubAPI {
+	if s.api != nil {
+		return s.api
+	}
+	return publicGitHub
 }
 
-func getCacheFileName(owner, sourceID string) string {
-	switch owner {
-	case "github":
-		return "github.json"
-	case "toptal":
-		return "toptal.json"
-	default:
-		return fmt.Sprintf("%s.json", sourceID)
+// repoID identifies the repository, including the host when it is not
+// on public GitHub
+func (s *GitHubSource) repoID() string {
+	if s.api != nil && s.api.host != "" {
+		return s.api.host + "/" + s.owner + "/" + s.repo
 	}
+	return s.owner + "/" + s.repo
 }
 
-func fetchFromGitHub(owner, repo, path string) ([]Template, error) {
-	ctx := context.Background()
-	contents, dirContents, _, err := githubClient.Repositories.GetContents(ctx, owner, repo, path, nil)
+func (s *GitHubSource) List() ([]Template, error) {
+	templates, err := s.FetchTemplates()
 	if err != nil {
 		return nil, err
 	}
+	return s.filter(templates), nil
+}
 
-	var templates []Template
-	if contents != nil {
-		templates = handleSingleFile(contents)
-	} else {
-		templates = handleDirectory(owner, repo, dirContents)
+func (s *GitHubSource) Fetch(name string) ([]byte, error) {
+	templates, err := s.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch templates from %s: %v", s.prefix, err)
+	}
+
+	tmpl, err := FindTemplate(name, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := GetTemplateContent(tmpl.DownloadURL)
+	if err != nil {
+		if content, ok := s.snapshotContent(tmpl.Path); ok {
+			return content, nil
+		}
+		return nil, fmt.Errorf("unable to fetch content: %v", err)
+	}
+	return content, nil
+}
+
+// Refresh re-fetches the repository listing and replaces the cached one
+func (s *GitHubSource) Refresh() error {
+	templates, validators, err := s.server().listTemplates(s.owner, s.repo, s.path, "", cache.Validators{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch templates: %w", err)
+	}
+
+	saveTemplatesCache(s.cacheFile, templates, validators, true)
+	return nil
+}
+
+func (s *GitHubSource) filter(templates []Template) []Template {
+	if s.match == nil {
+		return templates
+	}
+
+	var filtered []Template
+	for _, t := range templates {
+		if s.match(t) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// inDirectory matches templates stored directly in dir
+func inDirectory(dir string) func(Template) bool {
+	return func(t Template) bool {
+		parent := path.Dir(t.Path)
+		if parent == "." {
+			parent = ""
+		}
+		return parent == dir
+	}
+}
+
+// underDirectory matches templates stored anywhere below dir
+func underDirectory(dir string) func(Template) bool {
+	return func(t Template) bool {
+		return strings.HasPrefix(t.Path, dir+"/")
+	}
+}
+
+// FetchTemplates lists all templates in the repository, using the
+// source's cache file to avoid hitting the API on every call. An expired
+// listing is served right away and revalidated in the background. When
+// GitHub cannot be reached, or gignr is offline, the built-in
+// repositories are listed from the embedded snapshot.
+func (s *GitHubSource) FetchTemplates() ([]Template, error) {
+	if cached, ok := loadTemplatesCache(s.cacheFile); ok {
+		if cache.IsCacheExpired(cached.Updated) {
+			revalidate(s.cacheFile, func() error { return s.revalidateTemplates(cached) })
+		}
+		return cached.Templates, nil
+	}
+
+	templates, validators, err := s.server().listTemplates(s.owner, s.repo, s.path, "", cache.Validators{})
+	if err != nil {
+		if templates, ok := s.snapshotTemplates(); ok {
+			return templates, nil
+		}
+		return nil, s.withSnapshotHint(fmt.Errorf("failed to fetch templates: %w", err))
 	}
 
+	saveTemplatesCache(s.cacheFile, templates, validators, false)
 	return templates, nil
 }
 
-func handleSingleFile(content *github.RepositoryContent) []Template {
-	if !strings.HasSuffix(content.GetName(), ".gitignore") {
+// revalidateTemplates refreshes an expired listing with a conditional
+// request, keeping it when the repository has not changed
+func (s *GitHubSource) revalidateTemplates(cached TemplatesCache) error {
+	templates, validators, err := s.server().listTemplates(s.owner, s.repo, s.path, "", cached.Validators)
+	if errors.Is(err, errNotModified) {
+		touchTemplatesCache(s.cacheFile, cached)
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 
-	return []Template{{
-		Name:        content.GetName(),
-		Path:        content.GetPath(),
-		DownloadURL: content.GetDownloadURL(),
-		Source:      utils.DetectSource(content.GetURL()),
-	}}
+	saveTemplatesCache(s.cacheFile, templates, validators, true)
+	return nil
 }
 
-func handleDirectory(owner, repo string, contents []*github.RepositoryContent) []Template {
+// errNotModified is returned by listTemplates when a conditional
+// request finds the listing unchanged
+var errNotModified = errors.New("not modified")
+
+// listTemplates lists the templates under dir at ref, or on the
+// default branch when ref is empty. The whole repository is listed with
+// a single recursive Git Trees request where possible. When validators
+// are given the request is conditional, and errNotModified is returned if
+// the tree has not changed.
+func (g *githubAPI) listTemplates(owner, repo, dir, ref string, validators cache.Validators) ([]Template, cache.Validators, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	ctx := context.Background()
+	tree, resp, err := g.getTree(ctx, owner, repo, ref, true, validators)
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return nil, validators, errNotModified
+	}
+	if err != nil {
+		return nil, cache.Validators{}, err
+	}
+
+	entries, err := g.filesInTree(ctx, owner, repo, ref, "", dir, tree)
+	if err != nil {
+		return nil, cache.Validators{}, err
+	}
+
 	var templates []Template
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+	for _, entry := range entries {
+		name := path.Base(entry)
+		if !strings.HasSuffix(name, ".gitignore") || name == ".gitignore" {
+			continue
+		}
+		if dir != "" && !strings.HasPrefix(entry, dir+"/") {
+			continue
+		}
+
+		downloadURL := g.rawURL(owner, repo, ref, entry)
+		templates = append(templates, Template{
+			Name:        name,
+			Path:        entry,
+			DownloadURL: downloadURL,
+			Source:      utils.DetectSource(downloadURL),
+		})
+	}
 
-	for _, content := range contents {
-		if content.GetType() == "file" {
-			if tmpl := handleSingleFile(content); tmpl != nil {
-				templates = append(templates, tmpl...)
+	return templates, cache.ValidatorsFrom(resp.Response), nil
+}
+
+// getTree fetches a tree, optionally as a conditional request
+func (g *githubAPI) getTree(ctx context.Context, owner, repo, sha string, recursive bool, validators cache.Validators) (*github.Tree, *github.Response, error) {
+	u := fmt.Sprintf("repos/%v/%v/git/trees/%v", owner, repo, url.PathEscape(sha))
+	if recursive {
+		u += "?recursive=1"
+	}
+
+	req, err := g.client.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	validators.Apply(req)
+
+	tree := new(github.Tree)
+	resp, err := g.client.Do(ctx, req, tree)
+	if err != nil {
+		return nil, resp, err
+	}
+	return tree, resp, nil
+}
+
+// filesInTree returns the paths of all files in tree, prefixed with
+// prefix. When GitHub truncated the recursive listing, the tree is walked
+// one level at a time instead, only descending into directories that can
+// contain dir. Failures in subdirectories are collected and returned
+// rather than skipped.
+func (g *githubAPI) filesInTree(ctx context.Context, owner, repo, sha, prefix, dir string, tree *github.Tree) ([]string, error) {
+	var files []string
+	if !tree.GetTruncated() {
+		for _, entry := range tree.Entries {
+			if entry.GetType() == "blob" {
+				files = append(files, path.Join(prefix, entry.GetPath()))
 			}
-			continue
 		}
+		return files, nil
+	}
 
-		if content.GetType() == "dir" {
-			wg.Add(1)
-			go func(subPath string) {
-				defer wg.Done()
-				subTemplates, err := fetchFromGitHub(owner, repo, subPath)
-				if err != nil {
-					return
-				}
-				mu.Lock()
-				templates = append(templates, subTemplates...)
-				mu.Unlock()
-			}(content.GetPath())
+	tree, _, err := g.getTree(ctx, owner, repo, sha, false, cache.Validators{})
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, entry := range tree.Entries {
+		entryPath := path.Join(prefix, entry.GetPath())
+		switch entry.GetType() {
+		case "blob":
+			files = append(files, entryPath)
+		case "tree":
+			if !mayContain(entryPath, dir) {
+				continue
+			}
+			subFiles, err := g.listSubtree(ctx, owner, repo, entry.GetSHA(), entryPath, dir)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", entryPath, err))
+				continue
+			}
+			files = append(files, subFiles...)
 		}
 	}
 
-	wg.Wait()
-	return templates
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to list subdirectories: %w", errors.Join(errs...))
+	}
+	return files, nil
+}
+
+func (g *githubAPI) listSubtree(ctx context.Context, owner, repo, sha, prefix, dir string) ([]string, error) {
+	tree, _, err := g.getTree(ctx, owner, repo, sha, true, cache.Validators{})
+	if err != nil {
+		return nil, err
+	}
+	return g.filesInTree(ctx, owner, repo, sha, prefix, dir, tree)
+}
+
+// mayContain reports whether the directory at p is dir, inside it, or
+// one of its parents
+func mayContain(p, dir string) bool {
+	return dir == "" || p == dir || strings.HasPrefix(p, dir+"/") || strings.HasPrefix(dir, p+"/")
 }
@@ -1,11 +1,192 @@
 package templates
 
 import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/jasonuc/gignr/internal/auth"
 	"github.com/spf13/viper"
 )
 
-// Load user-added repositories using urls from config
-func LoadCustomRepositories() map[string]string {
-	repos := viper.GetStringMapString("repositories")
+// Repository kinds, selecting the API a repository is accessed through
+const (
+	KindGitHub    = "github"
+	KindGitLab    = "gitlab"
+	KindGitea     = "gitea"
+	KindBitbucket = "bitbucket"
+	// KindGit is any git remote, accessed through a local clone
+	KindGit = "git"
+	// KindDir is a directory on disk
+	KindDir = "dir"
+	// KindIndex is a static index.json file on a web server
+	KindIndex = "index"
+	// KindGist is a GitHub gist
+	KindGist = "gist"
+	// KindArchive is a .zip or .tar.gz file, given as a path or URL
+	KindArchive = "archive"
+)
+
+// RepositoryKinds lists the supported repository kinds
+var RepositoryKinds = []string{KindGitHub, KindGitLab, KindGitea, KindBitbucket, KindGit, KindDir, KindIndex, KindGist, KindArchive}
+
+// apiKinds are the kinds of repositories accessed through an API, the
+// only ones that take api_url, raw_url and token. Git remotes use the
+// credentials configured for git.
+var apiKinds = map[string]bool{KindGitHub: true, KindGitLab: true, KindGitea: true, KindBitbucket: true}
+
+// knownHosts maps public hosts to their kind. Repositories on other hosts
+// are treated as GitHub Enterprise unless a kind is configured.
+var knownHosts = map[string]string{
+	"github.com":    KindGitHub,
+	"gitlab.com":    KindGitLab,
+	"codeberg.org":  KindGitea,
+	"bitbucket.org": KindBitbucket,
+}
+
+// RepositoryConfig is a user-added repository. In the config it is either
+// the repository URL or a map with the url and optional settings:
+//
+//	repositories:
+//	  jc: https://github.com/jasonuc/gitignore
+//	  team: /srv/shared/gitignore-templates
+//	  platform: https://intranet/gitignore/index.json
+//	  mine: gist:aa5a315d61ae9438b18d
+//	  bundle: /opt/gignr/templates.tar.gz
+//	  corp:
+//	    url: https://git.corp/platform/gitignore
+//	    type: gitlab
+//	    api_url: https://git.corp/api/v4
+//	    token: glpat-...
+type RepositoryConfig struct {
+	URL string
+	// Type is one of RepositoryKinds, detected from the host when empty
+	Type   string
+	APIURL string
+	// RawURL is the base URL raw files are served from, GitHub only
+	RawURL string
+	// Token overrides the token looked up for the kind of repository
+	Token string
+}
+
+// Kind returns the configured kind of repository, or the one detected
+// from its URL
+func (c RepositoryConfig) Kind() string {
+	if c.Type != "" {
+		return strings.ToLower(c.Type)
+	}
+	if IsGistURL(c.URL) {
+		return KindGist
+	}
+	if IsGitURL(c.URL) {
+		return KindGit
+	}
+	if IsArchive(c.URL) {
+		return KindArchive
+	}
+	if IsIndexURL(c.URL) {
+		return KindIndex
+	}
+	if IsLocalPath(c.URL) {
+		return KindDir
+	}
+	if u, err := url.Parse(c.URL); err == nil {
+		if kind, ok := knownHosts[u.Hostname()]; ok {
+			return kind
+		}
+	}
+	return KindGitHub
+}
+
+// RepositorySource is a source backed by a user-added repository
+type RepositorySource interface {
+	Source
+	// HasGitignoreTemplates reports whether the repository holds named
+	// `.gitignore` files, e.g. python.gitignore
+	HasGitignoreTemplates() (bool, error)
+}
+
+// NewRepositorySource creates a source for a user-added repository
+func NewRepositorySource(nickname string, cfg RepositoryConfig) (RepositorySource, error) {
+	kind := cfg.Kind()
+	if !apiKinds[kind] && slices.Contains(RepositoryKinds, kind) && (cfg.APIURL != "" || cfg.RawURL != "" || cfg.Token != "") {
+		return nil, fmt.Errorf("%s repositories do not take api_url, raw_url or token", kind)
+	}
+
+	switch kind {
+	case KindGitHub:
+		return newGitHubRepository(nickname, cfg)
+	case KindGit:
+		return newGitSource(nickname, cfg.URL), nil
+	case KindDir:
+		return newDirectorySource(nickname, cfg.URL)
+	case KindIndex:
+		return newIndexSource(nickname, cfg.URL)
+	case KindGist:
+		return newGistSource(nickname, cfg.URL)
+	case KindArchive:
+		return newArchiveSource(nickname, cfg.URL)
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL")
+	}
+	if cfg.RawURL != "" {
+		return nil, fmt.Errorf("raw_url is only supported for GitHub repositories")
+	}
+
+	var f forge
+	switch kind {
+	case KindGitLab:
+		f, err = newGitLab(u, cfg.APIURL)
+	case KindGitea:
+		f, err = newGitea(u, cfg.APIURL)
+	case KindBitbucket:
+		f, err = newBitbucket(u, cfg.APIURL)
+	default:
+		return nil, fmt.Errorf("unknown repository type %q, must be one of %s", cfg.Type, strings.Join(RepositoryKinds, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token := cfg.Token
+	if token == "" {
+		token = auth.ForgeToken(kind)
+	}
+	if err := f.authenticate(token); err != nil {
+		return nil, err
+	}
+
+	return &ForgeSource{
+		prefix:    nickname,
+		cacheFile: fmt.Sprintf("%s.json", nickname),
+		forge:     f,
+	}, nil
+}
+
+// Load user-added repositories from config
+func LoadCustomRepositories() map[string]RepositoryConfig {
+	repos := map[string]RepositoryConfig{}
+	for nickname, value := range viper.GetStringMap("repositories") {
+		switch v := value.(type) {
+		case string:
+			repos[nickname] = RepositoryConfig{URL: v}
+		case map[string]interface{}:
+			str := func(key string) string {
+				s, _ := v[key].(string)
+				return s
+			}
+			repos[nickname] = RepositoryConfig{
+				URL:    str("url"),
+				Type:   str("type"),
+				APIURL: str("api_url"),
+				RawURL: str("raw_url"),
+				Token:  str("token"),
+			}
+		}
+	}
 	return repos
 }
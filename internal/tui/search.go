@@ -98,8 +98,7 @@ func (m *SearchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if !ok {
 				return m, tea.Quit
 			}
-			newSource := templateSrc(tab.tabs[tab.currentTab])
-			m.TemplateList, cmd = m.TemplateList.Update(sourceChangeMsg{newSource})
+			m.TemplateList, cmd = m.TemplateList.Update(sourceChangeMsg{tab.CurrentPrefix()})
 			cmds = append(cmds, cmd)
 		case "up", "down", "enter", " ":
 			m.TemplateList, cmd = m.TemplateList.Update(msg)
@@ -19,6 +19,7 @@ type AppStyle struct {
 	divider      lipgloss.Style
 	templateList lipgloss.Style
 	templateName lipgloss.Style
+	description  lipgloss.Style
 	selectedItem lipgloss.Style
 	noTemplates  lipgloss.Style
 	checkbox     lipgloss.Style
@@ -80,6 +81,9 @@ func (s *AppStyle) refresh() {
 	s.templateName = lipgloss.NewStyle().
 		Foreground(textColor)
 
+	s.description = lipgloss.NewStyle().
+		Foreground(mutedTextColor)
+
 	s.selectedItem = lipgloss.NewStyle().
 		Foreground(backgroundColor).
 		Background(primaryColor).
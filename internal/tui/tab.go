@@ -4,19 +4,27 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jasonuc/gignr/internal/templates"
 )
 
 type TabModel struct {
 	currentTab int
 	tabs       []string
+	prefixes   []string
 	styles     *AppStyle
 }
 
 func newTabModel(styles *AppStyle) *TabModel {
-	tabs := []string{"TopTal", "GitHub", "GitHub Community", "GitHub Global", "Others"}
+	var tabs, prefixes []string
+	for _, src := range templates.Sources() {
+		tabs = append(tabs, src.DisplayName())
+		prefixes = append(prefixes, src.Prefix())
+	}
+
 	return &TabModel{
 		currentTab: 0,
 		tabs:       tabs,
+		prefixes:   prefixes,
 		styles:     styles,
 	}
 }
@@ -64,6 +72,11 @@ func (m *TabModel) View() string {
 	return m.styles.tabSection.Render(tabContent.String())
 }
 
+// CurrentPrefix returns the prefix of the source shown in the active tab
+func (m *TabModel) CurrentPrefix() string {
+	return m.prefixes[m.currentTab]
+}
+
 func (m *TabModel) SetStyles(styles *AppStyle) {
 	m.styles = styles
 }
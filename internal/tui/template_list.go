@@ -12,7 +12,7 @@ import (
 
 type TemplateListModel struct {
 	Templates         CachedTemplates
-	ActiveSource      templateSrc
+	ActiveSource      string
 	viewport          viewport.Model
 	pageSize          int
 	currentPage       int
@@ -26,52 +26,42 @@ func newTemplateListModel(styles *AppStyle) *TemplateListModel {
 	viewportHeight := styles.templateList.GetHeight()
 
 	model := &TemplateListModel{
-		ActiveSource: TopTal,
-		Templates:    CachedTemplates{Sources: make(map[string]*SourceData)},
-		pageSize:     10,
-		currentPage:  0,
-		viewport:     viewport.New(viewportWidth, viewportHeight),
-		filterText:   "",
-		styles:       styles,
+		Templates:   CachedTemplates{Sources: make(map[string]*SourceData)},
+		pageSize:    10,
+		currentPage: 0,
+		viewport:    viewport.New(viewportWidth, viewportHeight),
+		filterText:  "",
+		styles:      styles,
 	}
 
 	model.viewport.KeyMap.PageDown.SetEnabled(false)
 
-	sources := []templateSrc{TopTal, GitHub, GitHubCommunity, GitHubGlobal, Others}
-	for _, source := range sources {
-		model.Templates.Sources[string(source)] = &SourceData{
+	for i, src := range templates.Sources() {
+		if i == 0 {
+			model.ActiveSource = src.Prefix()
+		}
+
+		sourceData := &SourceData{
 			Templates:    make([]TemplateEntry, 0),
 			CurrentIndex: 0,
 		}
-	}
+		model.Templates.Sources[src.Prefix()] = sourceData
 
-	if cacheFiles, err := listCacheFiles(); err == nil {
-		for filename, cacheFile := range cacheFiles {
-			if templates, err := templates.LoadCachedTemplates(cacheFile); err == nil {
-				for _, template := range templates {
-					sourceKey := mapTemplateSource(filename, template.Source)
-					if sourceData, exists := model.Templates.Sources[sourceKey]; exists {
-						sourceData.Templates = append(sourceData.Templates, TemplateEntry{
-							Name:     template.Name,
-							Selected: false,
-						})
-					}
-				}
-			}
+		list, err := src.List()
+		if err != nil {
+			continue
 		}
-	}
-
-	if localTemplates, err := listLocalTemplates(); err == nil {
-		othersData := model.Templates.Sources[string(Others)]
-		for _, file := range localTemplates {
-			othersData.Templates = append(othersData.Templates, TemplateEntry{
-				Name:     file,
-				Selected: false,
+		for _, template := range list {
+			sourceData.Templates = append(sourceData.Templates, TemplateEntry{
+				Name:        template.Name,
+				Description: template.Description,
+				Selected:    false,
+				Source:      src.Prefix(),
 			})
 		}
 	}
 
-	if sourceData := model.Templates.Sources[string(model.ActiveSource)]; sourceData != nil {
+	if sourceData := model.Templates.Sources[model.ActiveSource]; sourceData != nil {
 		model.filteredTemplates = sourceData.Templates
 	}
 
@@ -84,7 +74,7 @@ func (m *TemplateListModel) Init() tea.Cmd {
 
 func (m *TemplateListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
-	sourceData, exists := m.Templates.Sources[string(m.ActiveSource)]
+	sourceData, exists := m.Templates.Sources[m.ActiveSource]
 	if !exists {
 		return m, nil
 	}
@@ -138,7 +128,7 @@ func (m *TemplateListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *TemplateListModel) View() string {
-	sourceData := m.Templates.Sources[string(m.ActiveSource)]
+	sourceData := m.Templates.Sources[m.ActiveSource]
 	if sourceData == nil || len(m.filteredTemplates) == 0 {
 		message := "No templates found"
 		if m.filterText != "" {
@@ -171,7 +161,7 @@ func (m *TemplateListModel) View() string {
 }
 
 func (m *TemplateListModel) ensureVisibleItem() {
-	sourceData := m.Templates.Sources[string(m.ActiveSource)]
+	sourceData := m.Templates.Sources[m.ActiveSource]
 	itemHeight := 1
 	viewportStart := m.viewport.YOffset
 	viewportEnd := viewportStart + m.viewport.Height
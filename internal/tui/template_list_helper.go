@@ -2,30 +2,16 @@ package tui
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/atotto/clipboard"
-	"github.com/jasonuc/gignr/internal/cache"
-	"github.com/jasonuc/gignr/internal/templates"
-	"github.com/spf13/viper"
-)
-
-type templateSrc string
-
-const (
-	TopTal          templateSrc = "TopTal"
-	GitHub          templateSrc = "GitHub"
-	GitHubCommunity templateSrc = "GitHub Community"
-	GitHubGlobal    templateSrc = "GitHub Global"
-	Others          templateSrc = "Others"
 )
 
 type TemplateEntry struct {
-	Name     string
-	Selected bool
-	Source   string
+	Name        string
+	Description string
+	Selected    bool
+	Source      string
 }
 
 type SourceData struct {
@@ -38,7 +24,7 @@ type CachedTemplates struct {
 }
 
 type sourceChangeMsg struct {
-	NewSource templateSrc
+	NewSource string
 }
 
 func (m *TemplateListModel) renderTemplateItem(template TemplateEntry, isCurrent bool) string {
@@ -63,62 +49,11 @@ func (m *TemplateListModel) renderTemplateItem(template TemplateEntry, isCurrent
 	b.WriteString(prefix)
 	b.WriteString(m.styles.checkbox.Render(checkbox))
 	b.WriteString(name)
-
-	return b.String()
-}
-
-func mapTemplateSource(filename, source string) string {
-	lcFilename := strings.ToLower(filename)
-	if strings.Contains(lcFilename, "toptal") {
-		return string(TopTal)
-	}
-
-	repos := viper.GetStringMapString("repositories")
-	if _, exists := repos[strings.TrimSuffix(filename, ".json")]; exists {
-		return string(Others)
+	if template.Description != "" {
+		b.WriteString(m.styles.description.Render(" " + template.Description))
 	}
 
-	switch source {
-	case "GitHub Community":
-		return string(GitHubCommunity)
-	case "GitHub Global":
-		return string(GitHubGlobal)
-	case "GitHub":
-		return string(GitHub)
-	default:
-		return string(Others)
-	}
-}
-
-func listCacheFiles() (map[string]string, error) {
-	files, err := os.ReadDir(cache.GetCacheDir())
-	if err != nil {
-		return nil, err
-	}
-
-	sources := make(map[string]string)
-	for _, f := range files {
-		name := f.Name()
-		if strings.HasSuffix(name, ".json") {
-			sources[strings.TrimSuffix(name, ".json")] = name
-		}
-	}
-	return sources, nil
-}
-
-func listLocalTemplates() ([]string, error) {
-	files, err := os.ReadDir(filepath.Join(os.Getenv("HOME"), ".config/gignr/templates"))
-	if err != nil {
-		return nil, err
-	}
-
-	templates := make([]string, 0, len(files))
-	for _, f := range files {
-		if name := f.Name(); strings.HasSuffix(name, ".gitignore") {
-			templates = append(templates, name)
-		}
-	}
-	return templates, nil
+	return b.String()
 }
 
 func (m *TemplateListModel) GetSelectedTemplates() []TemplateEntry {
@@ -145,69 +80,28 @@ func (m *SearchModel) HandleSave() {
 
 func buildTemplateParts(templateList *TemplateListModel) []string {
 	var parts []string
-	for src, data := range templateList.Templates.Sources {
+	for _, data := range templateList.Templates.Sources {
 		for _, tmpl := range data.Templates {
 			if !tmpl.Selected {
 				continue
 			}
-			if part := formatTemplatePart(templateSrc(src), tmpl); part != "" {
-				parts = append(parts, part)
-			}
+			parts = append(parts, formatTemplatePart(tmpl))
 		}
 	}
 	return parts
 }
 
-func formatTemplatePart(source templateSrc, tmpl TemplateEntry) string {
-	prefix := getSourcePrefix(source, tmpl)
+func formatTemplatePart(tmpl TemplateEntry) string {
 	name := strings.TrimSuffix(tmpl.Name, ".gitignore")
-	if prefix == "" {
+	if tmpl.Source == "" {
 		return name
 	}
-	return prefix + name
-}
-
-func getSourcePrefix(source templateSrc, tmpl TemplateEntry) string {
-	switch source {
-	case GitHub:
-		return "gh:"
-	case GitHubCommunity:
-		return "ghc:"
-	case GitHubGlobal:
-		return "ghg:"
-	case TopTal:
-		return "tt:"
-	case Others:
-		return getOthersPrefix(tmpl)
-	default:
-		return ""
-	}
-}
-
-func getOthersPrefix(tmpl TemplateEntry) string {
-	storagePath := viper.GetString("templates.storage_path")
-	if _, err := os.Stat(filepath.Join(storagePath, tmpl.Name)); err == nil {
-		return ""
-	}
-
-	repos := viper.GetStringMapString("repositories")
-	for nick := range repos {
-		cached, err := templates.LoadCachedTemplates(fmt.Sprintf("%s.json", nick))
-		if err != nil {
-			continue
-		}
-		for _, t := range cached {
-			if t.Name == tmpl.Name {
-				return nick + ":"
-			}
-		}
-	}
-	return ""
+	return tmpl.Source + ":" + name
 }
 
 func (m *TemplateListModel) FilterTemplates(searchText string) {
 	m.filterText = searchText
-	src := m.Templates.Sources[string(m.ActiveSource)]
+	src := m.Templates.Sources[m.ActiveSource]
 	if src == nil {
 		return
 	}
@@ -2,26 +2,37 @@ package utils
 
 import (
 	"fmt"
-	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/spf13/viper"
 )
 
-func ExtractRepoDetails(url string) (owner, repo string, err error) {
-	url = strings.Replace(url, "https://raw.githubusercontent.com/", "", 1)
-	url = strings.Replace(url, "https://api.github.com/repos/", "", 1)
-	url = strings.Replace(url, "https://github.com/", "", 1)
+// ExtractRepoDetails returns the owner and name of the repository a URL
+// points at. Repository, API and raw file URLs of github.com and of GitHub
+// Enterprise servers are understood.
+func ExtractRepoDetails(rawURL string) (owner, repo string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("invalid URL")
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	isAPI := u.Host == "api.github.com"
+	// GitHub Enterprise serves the API under /api/v3
+	if len(parts) >= 2 && parts[0] == "api" && parts[1] == "v3" {
+		parts, isAPI = parts[2:], true
+	}
+	if isAPI && len(parts) > 0 && parts[0] == "repos" {
+		parts = parts[1:]
+	}
 
-	parts := strings.Split(url, "/")
-	if len(parts) < 2 {
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
 		return "", "", fmt.Errorf("invalid URL")
 	}
 
 	owner = parts[0]
-	repo = parts[1]
-
-	repo = strings.TrimSuffix(repo, ".git")
+	repo = strings.TrimSuffix(parts[1], ".git")
 	return owner, repo, nil
 }
 
@@ -56,10 +67,13 @@ func DetectSource(path string) string {
 		return "TopTal"
 	}
 
-	repos := viper.GetStringMapString("repositories")
-
-	for nickname, repoURL := range repos {
-		if MatchRepoURL(repoURL, path) {
+	for nickname, value := range viper.GetStringMap("repositories") {
+		// Repositories are stored as a URL or as a map holding the URL
+		repoURL, ok := value.(string)
+		if m, isMap := value.(map[string]interface{}); isMap {
+			repoURL, ok = m["url"].(string)
+		}
+		if ok && MatchRepoURL(repoURL, path) {
 			return "Custom (" + nickname + ")"
 		}
 	}
@@ -67,23 +81,18 @@ func DetectSource(path string) string {
 	return "Unknown"
 }
 
-func IsValidGitHubURL(url string) bool {
-	if len(url) > 19 && url[:19] == "https://github.com/" {
-		resp, err := http.Get(url)
-		if err != nil {
-			PrintWarning("Warning: Could not verify repository (network error). Assuming invalid.")
-			return false
-		}
-		defer resp.Body.Close()
-
-		return resp.StatusCode == http.StatusOK
+// IsValidRepositoryURL checks that a URL has the form
+// https://{host}/{user}/{repo}, where host is github.com or a GitHub
+// Enterprise server. Whether the repository exists is checked through the
+// API, which can authenticate.
+func IsValidRepositoryURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return false
 	}
-	return false
-}
 
-func IsReservedNickname(nickname string) bool {
-	reserved := map[string]bool{"gh": true, "ghc": true, "ghg": true, "tt": true}
-	return reserved[strings.ToLower(nickname)]
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	return len(parts) == 2 && parts[0] != "" && strings.TrimSuffix(parts[1], ".git") != ""
 }
 
 func IsValidNickname(nickname string) bool {
@@ -4,12 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"slices"
 	"strings"
+	"time"
 
+	"github.com/jasonuc/gignr/internal/httpclient"
 	"golang.org/x/mod/semver"
 )
 
@@ -77,6 +78,10 @@ func getLatestVersion() (string, error) {
 		proxies = append(proxies, goproxyDefault)
 	}
 
+	// The check runs on every invocation, so it must not hold up the CLI
+	// when the network is slow or missing
+	client := httpclient.New(httpclient.WithTimeout(5*time.Second), httpclient.WithRetries(0))
+
 	for _, proxy := range proxies {
 		proxy = strings.TrimSpace(proxy)
 		proxy = strings.TrimRight(proxy, "/")
@@ -85,7 +90,7 @@ func getLatestVersion() (string, error) {
 		}
 
 		url := fmt.Sprintf("%s/github.com/%s/%s/@latest", proxy, repoOwner, repoName)
-		resp, err := http.Get(url)
+		resp, err := client.Get(url)
 		if err != nil {
 			continue
 		}